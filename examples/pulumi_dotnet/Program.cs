@@ -16,7 +16,7 @@ return await Deployment.RunAsync(() =>
             SizeGB = 32,
             SkuRef = new ReferenceArgs
             {
-                Resource = "my-SKU-reference",
+                Resource = "skus/seca.rd500",
             },
         },
         Workspace = ws.Metadata.Apply(m => m.Name),
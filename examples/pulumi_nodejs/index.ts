@@ -6,7 +6,7 @@ const bs = new cape.storage.BlockStorage('myStorage', {
   spec: {
     sizeGB: 32,
     skuRef: {
-      resource: 'skus/standard',
+      resource: 'skus/seca.rd500',
     },
   },
 });
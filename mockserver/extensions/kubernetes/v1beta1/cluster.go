@@ -0,0 +1,26 @@
+package v1beta1
+
+import (
+	"net/http"
+
+	"cape-project.eu/mockserver/internal/store"
+	"cape-project.eu/mockserver/models"
+)
+
+// checkClusterDeletion refuses to delete clusters that still have node pools.
+func (s *server) checkClusterDeletion(ref store.Ref, _ models.KubernetesCluster) error {
+	nodePools := s.nodePools.List(store.Ref{Tenant: ref.Tenant, Workspace: ref.Workspace, Parent: "clusters/" + ref.Name})
+	if len(nodePools) > 0 {
+		return store.Errorf(http.StatusConflict, "cluster still has node pools, delete them first")
+	}
+	return nil
+}
+
+// setKubeconfig hands out a kubeconfig once a cluster is active.
+func setKubeconfig(cluster *models.KubernetesCluster, _, to string) {
+	if to != string(models.ResourceStateActive) || cluster.Status.Kubeconfig != nil || cluster.Metadata == nil {
+		return
+	}
+	kubeconfig := fakeKubeconfig(cluster.Metadata)
+	cluster.Status.Kubeconfig = &kubeconfig
+}
@@ -0,0 +1,54 @@
+package v1beta1
+
+import (
+	"net/http"
+	"strings"
+
+	"cape-project.eu/mockserver/internal/store"
+	"cape-project.eu/mockserver/models"
+	"github.com/gin-gonic/gin"
+)
+
+func (s *server) ListNodePools(c *gin.Context, tenant models.TenantPathParam, workspace models.WorkspacePathParam, cluster string, params ListNodePoolsParams) {
+	if !s.hasCluster(c, tenant, workspace, cluster) {
+		return
+	}
+	s.resources.ListNodePools(c, tenant, workspace, cluster, params)
+}
+
+func (s *server) DeleteNodePool(c *gin.Context, tenant models.TenantPathParam, workspace models.WorkspacePathParam, cluster string, name models.ResourcePathParam, params DeleteNodePoolParams) {
+	if !s.hasCluster(c, tenant, workspace, cluster) {
+		return
+	}
+	s.resources.DeleteNodePool(c, tenant, workspace, cluster, name, params)
+}
+
+func (s *server) GetNodePool(c *gin.Context, tenant models.TenantPathParam, workspace models.WorkspacePathParam, cluster string, name models.ResourcePathParam) {
+	if !s.hasCluster(c, tenant, workspace, cluster) {
+		return
+	}
+	s.resources.GetNodePool(c, tenant, workspace, cluster, name)
+}
+
+// hasCluster answers with 404 if the cluster addressed by a node pool
+// request does not exist.
+func (s *server) hasCluster(c *gin.Context, tenant models.TenantPathParam, workspace models.WorkspacePathParam, cluster string) bool {
+	if _, ok := s.clusters.Lookup(store.Ref{Tenant: string(tenant), Workspace: string(workspace), Name: cluster}); !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "cluster not found"})
+		return false
+	}
+	return true
+}
+
+// checkNodePoolCluster only lets node pools be created in clusters that exist
+// and are not being deleted.
+func (s *server) checkNodePoolCluster(ref store.Ref, _ *models.KubernetesNodePool) error {
+	cluster, ok := s.clusters.Lookup(store.Ref{Tenant: ref.Tenant, Workspace: ref.Workspace, Name: strings.TrimPrefix(ref.Parent, "clusters/")})
+	if !ok {
+		return store.Errorf(http.StatusNotFound, "cluster not found")
+	}
+	if cluster.Status != nil && cluster.Status.State == models.ResourceStateDeleting {
+		return store.Errorf(http.StatusConflict, "cluster is being deleted")
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package v1beta1
+
+import (
+	"fmt"
+	"time"
+
+	"cape-project.eu/mockserver/internal/store"
+	"cape-project.eu/mockserver/models"
+	"github.com/gin-gonic/gin"
+)
+
+// Clusters and node pools take noticeably longer to provision than the
+// foundation resources, like they do on real providers.
+const (
+	clusterCreatingDelay  = 1 * time.Second
+	clusterActiveDelay    = 8 * time.Second
+	clusterUpdateDelay    = 3 * time.Second
+	clusterDeletionDelay  = 4 * time.Second
+	nodePoolCreatingDelay = 500 * time.Millisecond
+	nodePoolActiveDelay   = 5 * time.Second
+	nodePoolUpdateDelay   = 3 * time.Second
+	nodePoolDeletionDelay = 3 * time.Second
+)
+
+type server struct {
+	*resources
+}
+
+func RegisterServer(router gin.IRouter) {
+	s := &server{resources: newResources()}
+	s.clusters.SetLifecycle(store.Lifecycle{
+		Creating: clusterCreatingDelay,
+		Active:   clusterActiveDelay,
+		Update:   clusterUpdateDelay,
+		Deletion: clusterDeletionDelay,
+	})
+	s.clusters.BeforeDelete(s.checkClusterDeletion)
+	s.clusters.OnStateChange(setKubeconfig)
+	s.nodePools.SetLifecycle(store.Lifecycle{
+		Creating: nodePoolCreatingDelay,
+		Active:   nodePoolActiveDelay,
+		Update:   nodePoolUpdateDelay,
+		Deletion: nodePoolDeletionDelay,
+	})
+	s.nodePools.BeforeWrite(s.checkNodePoolCluster)
+
+	RegisterHandlersWithOptions(router, s, GinServerOptions{
+		BaseURL: "/providers/seca.kubernetes",
+	})
+}
+
+// fakeKubeconfig renders a kubeconfig pointing to a made-up API server, good
+// enough for programs that pass it on but not for talking to a cluster.
+func fakeKubeconfig(metadata *models.RegionalWorkspaceResourceMetadata) string {
+	endpoint := fmt.Sprintf("https://%s.%s.%s.k8s.mock.invalid:6443", metadata.Name, metadata.Workspace, metadata.Tenant)
+	return fmt.Sprintf(`apiVersion: v1
+kind: Config
+clusters:
+- name: %[1]s
+  cluster:
+    server: %[2]s
+    insecure-skip-tls-verify: true
+contexts:
+- name: %[1]s
+  context:
+    cluster: %[1]s
+    user: %[1]s-admin
+current-context: %[1]s
+users:
+- name: %[1]s-admin
+  user:
+    token: mock-token-%[1]s
+`, metadata.Name, endpoint)
+}
@@ -0,0 +1,170 @@
+package v1
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"cape-project.eu/mockserver/internal/auth"
+	"cape-project.eu/mockserver/internal/store"
+	"cape-project.eu/mockserver/models"
+	"github.com/gin-gonic/gin"
+)
+
+// request is what a call of a provider API needs permission for.
+type request struct {
+	provider  string
+	tenant    string
+	workspace string
+	resource  string
+	verb      string
+}
+
+// EnforcePermissions only lets requests through that are granted to their
+// subject by a role assignment. The subject is taken from the bearer token,
+// see auth.Subject. Callers presenting the admin token are always allowed,
+// so that roles and assignments can be set up in the first place.
+func (s *Server) EnforcePermissions(adminToken string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		credential, ok := auth.BearerToken(c)
+		if !ok {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "missing bearer token"})
+			return
+		}
+		if auth.IsToken(credential, adminToken) {
+			c.Next()
+			return
+		}
+
+		req, ok := parseRequest(c.Request.Method, c.Request.URL.Path)
+		if !ok {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "only the admin token may access this path"})
+			return
+		}
+		// Tenant-less reads, like the region list, are open to every caller.
+		if req.tenant == "" && (req.verb == "list" || req.verb == "get") {
+			c.Next()
+			return
+		}
+
+		subject := auth.Subject(credential)
+		if !s.allows(subject, req) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"error": fmt.Sprintf("%s is not allowed to %s %s of %s", subject, req.verb, req.resource, req.provider),
+			})
+			return
+		}
+		c.Next()
+	}
+}
+
+func (s *Server) allows(subject string, req request) bool {
+	for _, assignment := range s.roleAssignments.List(store.Ref{Tenant: req.tenant}) {
+		if assignment.Status != nil && assignment.Status.State == models.ResourceStateDeleting {
+			continue
+		}
+		if !slices.Contains(assignment.Spec.Subs, subject) || !inScope(assignment.Spec.Scopes, req) {
+			continue
+		}
+		for _, ref := range assignment.Spec.Roles {
+			role, ok := s.roles.Lookup(store.Ref{Tenant: req.tenant, Name: roleName(ref)})
+			if !ok || role.Status != nil && role.Status.State == models.ResourceStateDeleting {
+				continue
+			}
+			for _, permission := range role.Spec.Permissions {
+				if grants(permission, req) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+func inScope(scopes []models.RoleAssignmentScope, req request) bool {
+	if len(scopes) == 0 {
+		return true
+	}
+	for _, scope := range scopes {
+		if matchesAny(scope.Tenants, req.tenant) && (req.workspace == "" || matchesAny(scope.Workspaces, req.workspace)) {
+			return true
+		}
+	}
+	return false
+}
+
+func grants(permission models.Permission, req request) bool {
+	provider := strings.TrimSuffix(permission.Provider, "/v1")
+	if provider != "*" && provider != req.provider {
+		return false
+	}
+	return matchesAny(&permission.Resources, req.resource) && matchesAny(&permission.Verb, req.verb)
+}
+
+// matchesAny reports whether value is in values; a missing or empty list and
+// a "*" entry match everything.
+func matchesAny(values *[]string, value string) bool {
+	if values == nil || len(*values) == 0 {
+		return true
+	}
+	return slices.Contains(*values, "*") || slices.Contains(*values, value)
+}
+
+// parseRequest maps a provider API call like
+// "PUT /providers/seca.network/v1/tenants/t/workspaces/w/networks/n/subnets/s"
+// to the provider, scope, resource and verb it needs permission for. The
+// resource is the collection addressed last ("subnets"), the verb is "list"
+// for collections, "get", "put" or "delete" for single resources and "post"
+// for actions like starting an instance.
+func parseRequest(method, path string) (request, bool) {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	if len(segments) < 4 || segments[0] != "providers" {
+		return request{}, false
+	}
+	req := request{provider: segments[1]}
+	segments = segments[3:]
+
+	if segments[0] == "tenants" {
+		if len(segments) < 3 {
+			return request{}, false
+		}
+		req.tenant = segments[1]
+		segments = segments[2:]
+		if segments[0] == "workspaces" && len(segments) >= 2 {
+			req.workspace = segments[1]
+			if len(segments) > 2 {
+				segments = segments[2:]
+			}
+		}
+	}
+
+	if method == http.MethodPost {
+		if len(segments)%2 == 0 {
+			return request{}, false
+		}
+		segments = segments[:len(segments)-1]
+	}
+	if len(segments) == 0 {
+		return request{}, false
+	}
+	if len(segments)%2 == 1 {
+		req.resource = segments[len(segments)-1]
+	} else {
+		req.resource = segments[len(segments)-2]
+	}
+
+	switch {
+	case method == http.MethodPost:
+		req.verb = "post"
+	case method == http.MethodPut:
+		req.verb = "put"
+	case method == http.MethodDelete:
+		req.verb = "delete"
+	case len(segments)%2 == 1:
+		req.verb = "list"
+	default:
+		req.verb = "get"
+	}
+	return req, true
+}
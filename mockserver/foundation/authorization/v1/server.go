@@ -0,0 +1,47 @@
+package v1
+
+import (
+	"net/http"
+	"strings"
+
+	"cape-project.eu/mockserver/internal/store"
+	"cape-project.eu/mockserver/models"
+	"github.com/gin-gonic/gin"
+)
+
+// Server stores roles and role assignments. Other APIs can be checked
+// against them with EnforcePermissions.
+type Server struct {
+	*resources
+}
+
+func NewServer() *Server {
+	s := &Server{resources: newResources()}
+	s.roleAssignments.BeforeWrite(s.checkRoles)
+	return s
+}
+
+func RegisterServer(router gin.IRouter, s *Server) {
+	RegisterHandlersWithOptions(router, s, GinServerOptions{
+		BaseURL: "/providers/seca.authorization",
+	})
+}
+
+// checkRoles refuses role assignments that refer to roles which do not exist.
+func (s *Server) checkRoles(ref store.Ref, roleAssignment *models.RoleAssignment) error {
+	for _, role := range roleAssignment.Spec.Roles {
+		if _, ok := s.roles.Lookup(store.Ref{Tenant: ref.Tenant, Name: roleName(role)}); !ok {
+			return store.Errorf(http.StatusUnprocessableEntity, "role %q not found", role)
+		}
+	}
+	return nil
+}
+
+// roleName accepts plain role names as well as references like
+// "roles/<name>" or "tenants/<tenant>/roles/<name>".
+func roleName(ref string) string {
+	if i := strings.LastIndex(ref, "/"); i >= 0 {
+		return ref[i+1:]
+	}
+	return ref
+}
@@ -1,224 +1,280 @@
 package v1
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
-	"sync"
+	"strconv"
 	"time"
 
+	"cape-project.eu/mockserver/internal/catalog"
+	"cape-project.eu/mockserver/internal/region"
+	"cape-project.eu/mockserver/internal/store"
 	"cape-project.eu/mockserver/models"
 	"github.com/gin-gonic/gin"
 )
 
 type server struct {
-	mu        sync.RWMutex
-	instances map[string]models.Instance
+	*resources
+	skus *catalog.Catalog[instanceSKUDefinition]
 }
 
-func RegisterServer(router gin.IRouter) {
-	RegisterHandlersWithOptions(router, &server{
-		instances: map[string]models.Instance{},
-	}, GinServerOptions{
-		BaseURL: "/providers/seca.compute",
-	})
-}
-
-func (s *server) ListSkus(c *gin.Context, _tenant models.TenantPathParam, _params ListSkusParams) {
-	c.JSON(http.StatusNotImplemented, gin.H{"error": "not implemented"})
+type instanceSKUDefinition struct {
+	name         string
+	tier         string
+	vcpu         int
+	ram          int
+	architecture string
 }
 
-func (s *server) GetSku(c *gin.Context, _tenant models.TenantPathParam, _name models.ResourcePathParam) {
-	c.JSON(http.StatusNotImplemented, gin.H{"error": "not implemented"})
+var instanceSKUCatalog = []instanceSKUDefinition{
+	{name: "seca.d2", tier: "D2", vcpu: 2, ram: 8, architecture: "amd64"},
+	{name: "seca.d4", tier: "D4", vcpu: 4, ram: 16, architecture: "amd64"},
+	{name: "seca.d8", tier: "D8", vcpu: 8, ram: 32, architecture: "amd64"},
+	{name: "seca.d16", tier: "D16", vcpu: 16, ram: 64, architecture: "amd64"},
+	{name: "seca.c4", tier: "C4", vcpu: 4, ram: 8, architecture: "amd64"},
+	{name: "seca.c8", tier: "C8", vcpu: 8, ram: 16, architecture: "amd64"},
+	{name: "seca.c16", tier: "C16", vcpu: 16, ram: 32, architecture: "amd64"},
+	{name: "seca.m4", tier: "M4", vcpu: 4, ram: 32, architecture: "amd64"},
+	{name: "seca.m8", tier: "M8", vcpu: 8, ram: 64, architecture: "amd64"},
+	{name: "seca.a2", tier: "A2", vcpu: 2, ram: 8, architecture: "arm64"},
+	{name: "seca.a4", tier: "A4", vcpu: 4, ram: 16, architecture: "arm64"},
+	{name: "seca.a8", tier: "A8", vcpu: 8, ram: 32, architecture: "arm64"},
 }
 
-func (s *server) ListInstances(c *gin.Context, tenant models.TenantPathParam, workspace models.WorkspacePathParam, _params ListInstancesParams) {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
-
-	items := make([]models.Instance, 0)
-	for _, instance := range s.instances {
-		if instance.Metadata == nil {
-			continue
-		}
-		if instance.Metadata.Tenant == tenant && instance.Metadata.Workspace == workspace {
-			items = append(items, instance)
-		}
+func RegisterServer(router gin.IRouter) {
+	s := &server{
+		resources: newResources(),
+		skus:      newSKUCatalog(),
 	}
+	s.instances.OnStateChange(setPowerState)
 
-	c.JSON(http.StatusOK, InstanceIterator{
-		Items: items,
-		Metadata: models.ResponseMetadata{
-			Provider: "seca.compute/v1",
-			Resource: fmt.Sprintf("tenants/%s/workspaces/%s/instances", tenant, workspace),
-			Verb:     "list",
-		},
+	RegisterHandlersWithOptions(router, s, GinServerOptions{
+		BaseURL: "/providers/seca.compute",
 	})
 }
 
-func (s *server) DeleteInstance(c *gin.Context, tenant models.TenantPathParam, workspace models.WorkspacePathParam, name models.ResourcePathParam, _params DeleteInstanceParams) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-
-	key := instanceKey(tenant, workspace, name)
-	if _, ok := s.instances[key]; !ok {
-		c.JSON(http.StatusNotFound, gin.H{"error": "instance not found"})
+func (s *server) ListSkus(c *gin.Context, tenant models.TenantPathParam, params ListSkusParams) {
+	defs := s.skus.All()
+	skus := make([]models.InstanceSku, 0, len(defs))
+	for _, def := range defs {
+		skus = append(skus, instanceSKUFromDefinition(tenant, def))
+	}
+	name := func(sku *models.InstanceSku) string { return sku.Metadata.Name }
+	skuLabels := func(sku *models.InstanceSku) map[string]string { return sku.Labels }
+	page, skipToken, err := store.Paginate(skus, name, skuLabels, store.NewListOptions(params.Labels, params.Limit, params.SkipToken))
+	if err != nil {
+		store.Fail(c, err)
 		return
 	}
 
-	delete(s.instances, key)
-	c.JSON(http.StatusAccepted, gin.H{
-		"deleted":   true,
-		"tenant":    tenant,
-		"workspace": workspace,
-		"name":      name,
+	c.JSON(http.StatusOK, SkuIterator{
+		Items: page,
+		Metadata: models.ResponseMetadata{
+			Provider:  "seca.compute/v1",
+			Resource:  fmt.Sprintf("tenants/%s/skus", tenant),
+			SkipToken: skipToken,
+			Verb:      "list",
+		},
 	})
 }
 
-func (s *server) GetInstance(c *gin.Context, tenant models.TenantPathParam, workspace models.WorkspacePathParam, name models.ResourcePathParam) {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
-
-	instance, ok := s.instances[instanceKey(tenant, workspace, name)]
+func (s *server) GetSku(c *gin.Context, tenant models.TenantPathParam, name models.ResourcePathParam) {
+	def, ok := s.skus.Get(name)
 	if !ok {
-		c.JSON(http.StatusNotFound, gin.H{"error": "instance not found"})
+		c.JSON(http.StatusNotFound, gin.H{"error": "sku not found"})
 		return
 	}
+	c.JSON(http.StatusOK, instanceSKUFromDefinition(tenant, def))
+}
 
-	c.JSON(http.StatusOK, instance)
+func (s *server) RestartInstance(c *gin.Context, tenant models.TenantPathParam, workspace models.WorkspacePathParam, name models.ResourcePathParam, _params RestartInstanceParams) {
+	s.changePowerState(c, tenant, workspace, name, restartAction)
 }
 
-func (s *server) CreateOrUpdateInstance(c *gin.Context, tenant models.TenantPathParam, workspace models.WorkspacePathParam, name models.ResourcePathParam, _params CreateOrUpdateInstanceParams) {
-	var instance models.Instance
-	if err := c.ShouldBindJSON(&instance); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		return
-	}
+func (s *server) StartInstance(c *gin.Context, tenant models.TenantPathParam, workspace models.WorkspacePathParam, name models.ResourcePathParam, _params StartInstanceParams) {
+	s.changePowerState(c, tenant, workspace, name, startAction)
+}
 
-	now := time.Now().UTC()
-
-	s.mu.Lock()
-	defer s.mu.Unlock()
-
-	key := instanceKey(tenant, workspace, name)
-	existing, exists := s.instances[key]
-	if !exists {
-		instance.Metadata = &models.RegionalWorkspaceResourceMetadata{
-			ApiVersion:      "v1",
-			CreatedAt:       now,
-			Kind:            "instance",
-			LastModifiedAt:  now,
-			Name:            name,
-			Provider:        "seca.compute",
-			Region:          "global",
-			Resource:        fmt.Sprintf("tenants/%s/workspaces/%s/instances/%s", tenant, workspace, name),
-			ResourceVersion: 1,
-			Tenant:          tenant,
-			Verb:            "put",
-			Workspace:       workspace,
-		}
-		setInstanceState(&instance, models.ResourceStatePending)
+func (s *server) StopInstance(c *gin.Context, tenant models.TenantPathParam, workspace models.WorkspacePathParam, name models.ResourcePathParam, _params StopInstanceParams) {
+	s.changePowerState(c, tenant, workspace, name, stopAction)
+}
 
-		s.instances[key] = instance
-		version := instance.Metadata.ResourceVersion
-		s.scheduleInstanceStateTransition(tenant, workspace, name, version, 100*time.Millisecond, models.ResourceStateCreating)
-		s.scheduleInstanceStateTransition(tenant, workspace, name, version, 600*time.Millisecond, models.ResourceStateActive)
-		c.JSON(http.StatusCreated, instance)
-		return
-	}
+// powerAction describes a power action. The condition recording it on the
+// instance carries its reason, which tells the power state the action ends in
+// from the stored instance alone.
+type powerAction struct {
+	verb        string
+	reason      string
+	target      models.InstanceStatusPowerState
+	transitions []powerTransition
+}
 
-	setInstanceState(&existing, models.ResourceStateActive)
-	s.instances[key] = existing
+// powerTransition is a step of a power action: after delay the instance
+// reaches powerState. The last step ends the action.
+type powerTransition struct {
+	delay      time.Duration
+	powerState models.InstanceStatusPowerState
+}
 
-	if existing.Metadata != nil {
-		instance.Metadata = existing.Metadata
-	} else {
-		instance.Metadata = &models.RegionalWorkspaceResourceMetadata{}
+var (
+	restartAction = powerAction{
+		verb:   "restarting",
+		reason: "restartRequested",
+		target: models.InstanceStatusPowerStateOn,
+		transitions: []powerTransition{
+			{delay: 1 * time.Second, powerState: models.InstanceStatusPowerStateOff},
+			{delay: 2500 * time.Millisecond, powerState: models.InstanceStatusPowerStateOn},
+		},
 	}
-
-	instance.Metadata.ApiVersion = "v1"
-	instance.Metadata.Kind = "instance"
-	instance.Metadata.Name = name
-	instance.Metadata.Provider = "seca.compute"
-	instance.Metadata.Region = "global"
-	instance.Metadata.Resource = fmt.Sprintf("tenants/%s/workspaces/%s/instances/%s", tenant, workspace, name)
-	instance.Metadata.Tenant = tenant
-	instance.Metadata.Verb = "put"
-	instance.Metadata.Workspace = workspace
-
-	if instance.Metadata.CreatedAt.IsZero() {
-		instance.Metadata.CreatedAt = now
+	startAction = powerAction{
+		verb:   "starting",
+		reason: "startRequested",
+		target: models.InstanceStatusPowerStateOn,
+		transitions: []powerTransition{
+			{delay: 1500 * time.Millisecond, powerState: models.InstanceStatusPowerStateOn},
+		},
 	}
-	instance.Metadata.LastModifiedAt = now
-	instance.Metadata.ResourceVersion++
-	if instance.Metadata.ResourceVersion == 0 {
-		instance.Metadata.ResourceVersion = 1
+	stopAction = powerAction{
+		verb:   "stopping",
+		reason: "stopRequested",
+		target: models.InstanceStatusPowerStateOff,
+		transitions: []powerTransition{
+			{delay: 1500 * time.Millisecond, powerState: models.InstanceStatusPowerStateOff},
+		},
 	}
-	setInstanceState(&instance, models.ResourceStateUpdating)
-
-	s.instances[key] = instance
-	version := instance.Metadata.ResourceVersion
-	s.scheduleInstanceStateTransition(tenant, workspace, name, version, 500*time.Millisecond, models.ResourceStateActive)
-	c.JSON(http.StatusOK, instance)
-}
+	powerActions = []powerAction{restartAction, startAction, stopAction}
+)
 
-func (s *server) RestartInstance(c *gin.Context, _tenant models.TenantPathParam, _workspace models.WorkspacePathParam, _name models.ResourcePathParam, _params RestartInstanceParams) {
-	c.JSON(http.StatusNotImplemented, gin.H{"error": "not implemented"})
-}
+// changePowerState runs a power action. While it is in progress the instance
+// is updating and refuses other power actions; starting a running or
+// stopping a stopped instance does nothing.
+func (s *server) changePowerState(c *gin.Context, tenant models.TenantPathParam, workspace models.WorkspacePathParam, name models.ResourcePathParam, action powerAction) {
+	ref := store.Ref{Tenant: string(tenant), Workspace: string(workspace), Name: string(name)}
+
+	changed := false
+	instance, err := s.instances.Modify(ref, func(instance *models.Instance) error {
+		if instance.Status == nil || instance.Status.State != models.ResourceStateActive {
+			state := models.ResourceStatePending
+			if instance.Status != nil {
+				state = instance.Status.State
+			}
+			return store.Errorf(http.StatusConflict, "instance is %s, power actions require it to be active", state)
+		}
+		if len(action.transitions) == 1 && instance.Status.PowerState == action.target {
+			return nil
+		}
 
-func (s *server) StartInstance(c *gin.Context, _tenant models.TenantPathParam, _workspace models.WorkspacePathParam, _name models.ResourcePathParam, _params StartInstanceParams) {
-	c.JSON(http.StatusNotImplemented, gin.H{"error": "not implemented"})
-}
+		s.instances.SetState(instance, string(models.ResourceStateUpdating))
+		s.instances.AddCondition(instance, string(models.ResourceStateUpdating), fmt.Sprintf("Instance is %s", action.verb), action.reason)
+		changed = true
+		return nil
+	})
+	if err != nil {
+		store.Fail(c, err)
+		return
+	}
 
-func (s *server) StopInstance(c *gin.Context, _tenant models.TenantPathParam, _workspace models.WorkspacePathParam, _name models.ResourcePathParam, _params StopInstanceParams) {
-	c.JSON(http.StatusNotImplemented, gin.H{"error": "not implemented"})
+	if changed {
+		for i, transition := range action.transitions {
+			s.scheduleInstancePowerTransition(ref, instance.Metadata.ResourceVersion, transition, i == len(action.transitions)-1)
+		}
+	}
+	c.JSON(http.StatusAccepted, instance)
 }
 
-func (s *server) scheduleInstanceStateTransition(tenant models.TenantPathParam, workspace models.WorkspacePathParam, name models.ResourcePathParam, version int64, delay time.Duration, state models.ResourceState) {
-	go func() {
-		time.Sleep(delay)
-
-		s.mu.Lock()
-		defer s.mu.Unlock()
-
-		key := instanceKey(tenant, workspace, name)
-		instance, ok := s.instances[key]
-		if !ok {
+func (s *server) scheduleInstancePowerTransition(ref store.Ref, version int64, transition powerTransition, last bool) {
+	s.instances.Schedule(ref, version, transition.delay, func(instance *models.Instance) {
+		if instance.Status == nil || instance.Status.State != models.ResourceStateUpdating {
 			return
 		}
 
-		if instance.Metadata == nil || instance.Metadata.ResourceVersion != version {
-			return
+		instance.Status.PowerState = transition.powerState
+		s.instances.AddCondition(instance, string(models.ResourceStateUpdating), fmt.Sprintf("Instance is now powered %s", transition.powerState), "powerStateChange")
+		if last {
+			s.instances.SetState(instance, string(models.ResourceStateActive))
 		}
-
-		setInstanceState(&instance, state)
-		s.instances[key] = instance
-	}()
+	})
 }
 
-func setInstanceState(instance *models.Instance, state models.ResourceState) {
-	if instance.Status == nil {
-		instance.Status = &models.InstanceStatus{
-			Conditions: []models.StatusCondition{},
-			PowerState: models.InstanceStatusPowerStateOff,
+// setPowerState keeps the power state in line with the lifecycle: instances
+// are off until they are provisioned and boot as soon as they are. An update
+// that ends settles the last power action, which completes actions the store
+// resumed after a restart, when their scheduled transitions were lost.
+func setPowerState(instance *models.Instance, from, to string) {
+	switch {
+	case from == "":
+		instance.Status.PowerState = models.InstanceStatusPowerStateOff
+	case from == string(models.ResourceStateCreating) && to == string(models.ResourceStateActive):
+		instance.Status.PowerState = models.InstanceStatusPowerStateOn
+	case from == string(models.ResourceStateUpdating) && to == string(models.ResourceStateActive):
+		if action, ok := lastPowerAction(instance); ok {
+			instance.Status.PowerState = action.target
 		}
 	}
-	if instance.Status.Conditions == nil {
-		instance.Status.Conditions = []models.StatusCondition{}
-	}
-	if instance.Status.State == state {
-		return
+}
+
+// lastPowerAction returns the last power action run on an instance, found by
+// the reason of the condition that recorded it.
+func lastPowerAction(instance *models.Instance) (powerAction, bool) {
+	conditions := instance.Status.Conditions
+	for i := len(conditions) - 1; i >= 0; i-- {
+		for _, action := range powerActions {
+			if conditions[i].Reason == action.reason {
+				return action, true
+			}
+		}
 	}
+	return powerAction{}, false
+}
 
-	instance.Status.State = state
+func instanceSKUFromDefinition(tenant models.TenantPathParam, def instanceSKUDefinition) models.InstanceSku {
+	return models.InstanceSku{
+		Labels: models.Labels{
+			"provider":     "seca",
+			"tier":         def.tier,
+			"architecture": def.architecture,
+			"vcpu":         strconv.Itoa(def.vcpu),
+			"ram":          strconv.Itoa(def.ram),
+		},
+		Metadata: &models.SkuResourceMetadata{
+			ApiVersion: "v1",
+			Kind:       models.SkuResourceMetadataKindResourceKindInstanceSku,
+			Name:       def.name,
+			Provider:   "seca.compute/v1",
+			Region:     region.Default(),
+			Resource:   fmt.Sprintf("tenants/%s/skus/%s", tenant, def.name),
+			Tenant:     tenant,
+			Verb:       "get",
+		},
+		Spec: &models.InstanceSkuSpec{
+			Ram:  def.ram,
+			VCPU: def.vcpu,
+		},
+	}
+}
 
-	instance.Status.Conditions = append(instance.Status.Conditions, models.StatusCondition{
-		LastTransitionAt: time.Now().UTC(),
-		Message:          fmt.Sprintf("Instance is now in %s state", state),
-		Reason:           "stateChange",
-		State:            state,
-	})
+func newSKUCatalog() *catalog.Catalog[instanceSKUDefinition] {
+	return catalog.New("seca.compute", string(models.SkuResourceMetadataKindResourceKindInstanceSku), "skus", instanceSKUCatalog, func(def instanceSKUDefinition) string {
+		return def.name
+	}, instanceSKUDefinitionFromFixture)
 }
 
-func instanceKey(tenant models.TenantPathParam, workspace models.WorkspacePathParam, name models.ResourcePathParam) string {
-	return fmt.Sprintf("%s-%s-%s", tenant, workspace, name)
+// instanceSKUDefinitionFromFixture reads a SKU seeded through the admin API.
+func instanceSKUDefinitionFromFixture(raw json.RawMessage) (instanceSKUDefinition, error) {
+	var sku models.InstanceSku
+	if err := json.Unmarshal(raw, &sku); err != nil {
+		return instanceSKUDefinition{}, err
+	}
+	if sku.Metadata == nil || sku.Metadata.Name == "" || sku.Spec == nil {
+		return instanceSKUDefinition{}, errors.New("sku needs metadata.name and spec")
+	}
+	return instanceSKUDefinition{
+		name:         sku.Metadata.Name,
+		tier:         sku.Labels["tier"],
+		vcpu:         sku.Spec.VCPU,
+		ram:          sku.Spec.Ram,
+		architecture: sku.Labels["architecture"],
+	}, nil
 }
@@ -0,0 +1,171 @@
+package v1
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"cape-project.eu/mockserver/internal/store"
+	"cape-project.eu/mockserver/models"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	testTenant    = "power"
+	testWorkspace = "ws"
+)
+
+// savedBackend holds resources saved by an earlier run of the mockserver.
+type savedBackend map[string]map[string]json.RawMessage
+
+func (b savedBackend) Load(namespace string) (map[string]json.RawMessage, error) {
+	return b[namespace], nil
+}
+
+func (savedBackend) Save(string, string, json.RawMessage) error {
+	return nil
+}
+
+func (savedBackend) Delete(string, string) error {
+	return nil
+}
+
+// savedInstance is an instance as saved in the given state, with the
+// conditions of the given reasons.
+type savedInstance struct {
+	name       string
+	state      models.ResourceState
+	powerState models.InstanceStatusPowerState
+	reasons    []string
+}
+
+// restoredServer creates a server that restores the given instances, as it
+// does when the mockserver restarts.
+func restoredServer(t *testing.T, instances ...savedInstance) *server {
+	t.Helper()
+	saved := map[string]json.RawMessage{}
+	for _, instance := range instances {
+		conditions := make([]gin.H, len(instance.reasons))
+		for i, reason := range instance.reasons {
+			conditions[i] = gin.H{"lastTransitionAt": time.Now().UTC(), "reason": reason, "state": instance.state}
+		}
+		raw, err := json.Marshal(gin.H{
+			"metadata": gin.H{"name": instance.name, "tenant": testTenant, "workspace": testWorkspace, "resourceVersion": 1},
+			"status":   gin.H{"state": instance.state, "powerState": instance.powerState, "conditions": conditions},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		saved[fmt.Sprintf("tenants/%s/workspaces/%s/instances/%s", testTenant, testWorkspace, instance.name)] = raw
+	}
+
+	store.Configure(savedBackend{"seca.compute/v1/instances": saved})
+	t.Cleanup(func() { store.Configure(store.Memory{}) })
+	s := &server{resources: newResources(), skus: newSKUCatalog()}
+	s.instances.OnStateChange(setPowerState)
+	return s
+}
+
+// waitForActive returns the instance once it is active again.
+func waitForActive(t *testing.T, s *server, name string) models.Instance {
+	t.Helper()
+	ref := store.Ref{Tenant: testTenant, Workspace: testWorkspace, Name: name}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		instance, ok := s.instances.Lookup(ref)
+		if !ok {
+			t.Fatalf("instance %s not found", name)
+		}
+		if instance.Status.State == models.ResourceStateActive {
+			return instance
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("instance %s is still %s", name, instance.Status.State)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+func TestPowerActions(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	on, off := models.InstanceStatusPowerStateOn, models.InstanceStatusPowerStateOff
+	s := restoredServer(t,
+		savedInstance{name: "start-stopped", state: models.ResourceStateActive, powerState: off},
+		savedInstance{name: "start-running", state: models.ResourceStateActive, powerState: on},
+		savedInstance{name: "stop-running", state: models.ResourceStateActive, powerState: on},
+		savedInstance{name: "restart-running", state: models.ResourceStateActive, powerState: on},
+		savedInstance{name: "stop-failed", state: models.ResourceStateError, powerState: off},
+	)
+
+	tests := []struct {
+		name       string
+		action     powerAction
+		wantStatus int
+		wantState  models.ResourceState
+		wantPower  models.InstanceStatusPowerState
+	}{
+		{name: "start-stopped", action: startAction, wantStatus: http.StatusAccepted, wantState: models.ResourceStateUpdating, wantPower: on},
+		{name: "start-running", action: startAction, wantStatus: http.StatusAccepted, wantState: models.ResourceStateActive, wantPower: on},
+		{name: "stop-running", action: stopAction, wantStatus: http.StatusAccepted, wantState: models.ResourceStateUpdating, wantPower: off},
+		{name: "restart-running", action: restartAction, wantStatus: http.StatusAccepted, wantState: models.ResourceStateUpdating, wantPower: on},
+		{name: "stop-failed", action: stopAction, wantStatus: http.StatusConflict},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodPost, "/", nil)
+			s.changePowerState(c, testTenant, testWorkspace, models.ResourcePathParam(tt.name), tt.action)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+			if w.Code != http.StatusAccepted {
+				return
+			}
+
+			var accepted models.Instance
+			if err := json.Unmarshal(w.Body.Bytes(), &accepted); err != nil {
+				t.Fatal(err)
+			}
+			if accepted.Status.State != tt.wantState {
+				t.Errorf("state = %s, want %s", accepted.Status.State, tt.wantState)
+			}
+			if instance := waitForActive(t, s, tt.name); instance.Status.PowerState != tt.wantPower {
+				t.Errorf("power state = %s, want %s", instance.Status.PowerState, tt.wantPower)
+			}
+		})
+	}
+}
+
+func TestRestoredPowerActions(t *testing.T) {
+	on, off := models.InstanceStatusPowerStateOn, models.InstanceStatusPowerStateOff
+
+	// The transitions scheduled for these actions were lost with the
+	// mockserver that saved them.
+	tests := []struct {
+		instance  savedInstance
+		wantPower models.InstanceStatusPowerState
+	}{
+		{instance: savedInstance{name: "starting", powerState: off, reasons: []string{"stateChange", startAction.reason}}, wantPower: on},
+		{instance: savedInstance{name: "stopping", powerState: on, reasons: []string{"stateChange", stopAction.reason}}, wantPower: off},
+		{instance: savedInstance{name: "restarting", powerState: off, reasons: []string{"stateChange", restartAction.reason, "powerStateChange"}}, wantPower: on},
+		{instance: savedInstance{name: "stopped-then-updated", powerState: off, reasons: []string{stopAction.reason, "powerStateChange", "stateChange", "stateChange"}}, wantPower: off},
+		{instance: savedInstance{name: "updated", powerState: on, reasons: []string{"stateChange"}}, wantPower: on},
+	}
+	instances := make([]savedInstance, len(tests))
+	for i, tt := range tests {
+		tt.instance.state = models.ResourceStateUpdating
+		instances[i] = tt.instance
+	}
+	s := restoredServer(t, instances...)
+
+	for _, tt := range tests {
+		if instance := waitForActive(t, s, tt.instance.name); instance.Status.PowerState != tt.wantPower {
+			t.Errorf("%s: power state = %s, want %s", tt.instance.name, instance.Status.PowerState, tt.wantPower)
+		}
+	}
+}
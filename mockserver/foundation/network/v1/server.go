@@ -0,0 +1,125 @@
+package v1
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"cape-project.eu/mockserver/internal/catalog"
+	"cape-project.eu/mockserver/internal/region"
+	"cape-project.eu/mockserver/internal/store"
+	"cape-project.eu/mockserver/models"
+	"github.com/gin-gonic/gin"
+)
+
+type server struct {
+	*resources
+	skus *catalog.Catalog[networkSKUDefinition]
+}
+
+type networkSKUDefinition struct {
+	name      string
+	tier      string
+	bandwidth int
+	packets   int
+}
+
+var networkSKUCatalog = []networkSKUDefinition{
+	{name: "seca.n1k", tier: "N1K", bandwidth: 1000, packets: 100000},
+	{name: "seca.n5k", tier: "N5K", bandwidth: 5000, packets: 500000},
+	{name: "seca.n10k", tier: "N10K", bandwidth: 10000, packets: 1000000},
+	{name: "seca.n25k", tier: "N25K", bandwidth: 25000, packets: 2500000},
+}
+
+func RegisterServer(router gin.IRouter) {
+	RegisterHandlersWithOptions(router, &server{
+		resources: newResources(),
+		skus:      newSKUCatalog(),
+	}, GinServerOptions{
+		BaseURL: "/providers/seca.network",
+	})
+}
+
+func (s *server) ListSkus(c *gin.Context, tenant models.TenantPathParam, params ListSkusParams) {
+	defs := s.skus.All()
+	skus := make([]models.NetworkSku, 0, len(defs))
+	for _, def := range defs {
+		skus = append(skus, networkSKUFromDefinition(tenant, def))
+	}
+	name := func(sku *models.NetworkSku) string { return sku.Metadata.Name }
+	skuLabels := func(sku *models.NetworkSku) map[string]string { return sku.Labels }
+	page, skipToken, err := store.Paginate(skus, name, skuLabels, store.NewListOptions(params.Labels, params.Limit, params.SkipToken))
+	if err != nil {
+		store.Fail(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, SkuIterator{
+		Items: page,
+		Metadata: models.ResponseMetadata{
+			Provider:  "seca.network/v1",
+			Resource:  fmt.Sprintf("tenants/%s/skus", tenant),
+			SkipToken: skipToken,
+			Verb:      "list",
+		},
+	})
+}
+
+func (s *server) GetSku(c *gin.Context, tenant models.TenantPathParam, name models.ResourcePathParam) {
+	def, ok := s.skus.Get(name)
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "sku not found"})
+		return
+	}
+	c.JSON(http.StatusOK, networkSKUFromDefinition(tenant, def))
+}
+
+func networkSKUFromDefinition(tenant models.TenantPathParam, def networkSKUDefinition) models.NetworkSku {
+	return models.NetworkSku{
+		Labels: models.Labels{
+			"provider":  "seca",
+			"tier":      def.tier,
+			"bandwidth": strconv.Itoa(def.bandwidth),
+			"packets":   strconv.Itoa(def.packets),
+		},
+		Metadata: &models.SkuResourceMetadata{
+			ApiVersion: "v1",
+			Kind:       models.SkuResourceMetadataKindResourceKindNetworkSku,
+			Name:       def.name,
+			Provider:   "seca.network/v1",
+			Region:     region.Default(),
+			Resource:   fmt.Sprintf("tenants/%s/skus/%s", tenant, def.name),
+			Tenant:     tenant,
+			Verb:       "get",
+		},
+		Spec: &models.NetworkSkuSpec{
+			Bandwidth: def.bandwidth,
+			Packets:   def.packets,
+		},
+	}
+}
+
+func newSKUCatalog() *catalog.Catalog[networkSKUDefinition] {
+	return catalog.New("seca.network", string(models.SkuResourceMetadataKindResourceKindNetworkSku), "skus", networkSKUCatalog, func(def networkSKUDefinition) string {
+		return def.name
+	}, networkSKUDefinitionFromFixture)
+}
+
+// networkSKUDefinitionFromFixture reads a SKU seeded through the admin API.
+func networkSKUDefinitionFromFixture(raw json.RawMessage) (networkSKUDefinition, error) {
+	var sku models.NetworkSku
+	if err := json.Unmarshal(raw, &sku); err != nil {
+		return networkSKUDefinition{}, err
+	}
+	if sku.Metadata == nil || sku.Metadata.Name == "" || sku.Spec == nil {
+		return networkSKUDefinition{}, errors.New("sku needs metadata.name and spec")
+	}
+	return networkSKUDefinition{
+		name:      sku.Metadata.Name,
+		tier:      sku.Labels["tier"],
+		bandwidth: sku.Spec.Bandwidth,
+		packets:   sku.Spec.Packets,
+	}, nil
+}
@@ -0,0 +1,82 @@
+package v1
+
+import (
+	"fmt"
+	"net/http"
+
+	"cape-project.eu/mockserver/internal/region"
+	"cape-project.eu/mockserver/internal/store"
+	"cape-project.eu/mockserver/models"
+	"github.com/gin-gonic/gin"
+)
+
+type server struct{}
+
+func RegisterServer(router gin.IRouter) {
+	RegisterHandlersWithOptions(router, &server{}, GinServerOptions{
+		BaseURL: "/providers/seca.region",
+	})
+}
+
+func (s *server) ListRegions(c *gin.Context, params ListRegionsParams) {
+	catalog := region.Catalog()
+	items := make([]models.Region, 0, len(catalog))
+	for _, def := range catalog {
+		items = append(items, regionFromDefinition(def))
+	}
+	name := func(region *models.Region) string { return region.Metadata.Name }
+	page, skipToken, err := store.Paginate(items, name, nil, store.NewListOptions(params.Labels, params.Limit, params.SkipToken))
+	if err != nil {
+		store.Fail(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, RegionIterator{
+		Items: page,
+		Metadata: models.ResponseMetadata{
+			Provider:  "seca.region/v1",
+			Resource:  "regions",
+			SkipToken: skipToken,
+			Verb:      "list",
+		},
+	})
+}
+
+func (s *server) GetRegion(c *gin.Context, name models.ResourcePathParam) {
+	for _, def := range region.Catalog() {
+		if def.Name == name {
+			c.JSON(http.StatusOK, regionFromDefinition(def))
+			return
+		}
+	}
+	c.JSON(http.StatusNotFound, gin.H{"error": "region not found"})
+}
+
+func regionFromDefinition(def region.Region) models.Region {
+	providers := make([]models.Provider, 0, len(def.Providers))
+	for _, provider := range def.Providers {
+		providers = append(providers, models.Provider{
+			Name:    provider.Name,
+			Url:     provider.URL,
+			Version: provider.Version,
+		})
+	}
+
+	zones := make([]string, len(def.Zones))
+	copy(zones, def.Zones)
+
+	return models.Region{
+		Metadata: &models.GlobalResourceMetadata{
+			ApiVersion: "v1",
+			Kind:       "region",
+			Name:       def.Name,
+			Provider:   "seca.region",
+			Resource:   fmt.Sprintf("regions/%s", def.Name),
+			Verb:       "get",
+		},
+		Spec: models.RegionSpec{
+			AvailableZones: zones,
+			Providers:      providers,
+		},
+	}
+}
@@ -0,0 +1,683 @@
+//go:build ignore
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"text/template"
+	"unicode"
+
+	"github.com/pb33f/libopenapi"
+	"github.com/pb33f/libopenapi/datamodel"
+	"github.com/pb33f/libopenapi/datamodel/high/base"
+	v3high "github.com/pb33f/libopenapi/datamodel/high/v3"
+)
+
+const SpecDir = "../ext/secapi/spec"
+const ModulePath = "cape-project.eu/mockserver"
+
+// PreconditionParam is the header parameter carrying the resource version a
+// CreateOrUpdate or Delete is conditional on.
+const PreconditionParam = "if-unmodified-since"
+
+var templateFuncs = template.FuncMap{
+	"join": strings.Join,
+}
+
+var mockTemplate = template.Must(template.New("mock.tmpl").Funcs(templateFuncs).ParseFiles("internal/codegen/mock.tmpl"))
+var serversTemplate = template.Must(template.New("servers.tmpl").Funcs(templateFuncs).ParseFiles("internal/codegen/servers.tmpl"))
+
+// metadataFields are the metadata properties maintained by the store, in
+// the order they are written.
+var metadataFields = []struct {
+	Property string
+	Field    string
+	Kind     string
+}{
+	{"apiVersion", "APIVersion", "string"},
+	{"kind", "Kind", "string"},
+	{"provider", "Provider", "string"},
+	{"region", "Region", "string"},
+	{"tenant", "Tenant", "string"},
+	{"workspace", "Workspace", "string"},
+	{"name", "Name", "string"},
+	{"resource", "Resource", "string"},
+	{"resourceVersion", "ResourceVersion", "int64"},
+	{"verb", "Verb", "string"},
+	{"createdAt", "CreatedAt", "time"},
+	{"lastModifiedAt", "LastModifiedAt", "time"},
+}
+
+type param struct {
+	Name string
+	Type string
+}
+
+type operation struct {
+	ID         string
+	Method     string
+	Path       string
+	PathParams []param
+	HasParams  bool
+	// Query maps the names of the query parameters to whether they are
+	// required.
+	Query map[string]bool
+	// Signature is the parameter list of the handler.
+	Signature string
+	// StubSignature is the parameter list with all parameters unused.
+	StubSignature string
+	// Ref is the store.Ref literal addressing the resource or collection.
+	Ref string
+	// PreconditionField is the field of the parameters holding the
+	// precondition, if the operation has one.
+	PreconditionField string
+	// Precondition is the expression passing the precondition to the store.
+	Precondition string
+}
+
+type resource struct {
+	Name       string
+	KindVar    string
+	Field      string
+	KindName   string
+	Collection string
+	Model      string
+	Iterator   string
+	ListMeta   string
+	// ListOptions is the expression collecting the store.ListOptions of the
+	// list operation; ListSkipToken tells whether its metadata has a
+	// skipToken to continue with.
+	ListOptions   string
+	ListSkipToken bool
+	// MetadataGet and MetadataSet hold the statements copying the model's
+	// metadata from and to store.Metadata.
+	MetadataGet []string
+	MetadataSet []string
+	Metadata    string
+	// Labels holds the statements returning the model's labels.
+	Labels    []string
+	Status    string
+	State     string
+	Condition string
+	// References are the paths of the reference properties of the resource.
+	References []string
+	List       *operation
+	Get        *operation
+	Put        *operation
+	Delete     *operation
+}
+
+type apiDef struct {
+	Package          string
+	Folder           string
+	ImportPath       string
+	Alias            string
+	Provider         string
+	Version          string
+	BaseURL          string
+	States           string
+	Resources        []*resource
+	Others           []*operation
+	HandWritten      bool
+	UsesModels       bool
+	UsesSlices       bool
+	UsesPrecondition bool
+}
+
+func main() {
+	cwd, _ := os.Getwd()
+	specRoot := SpecDir
+	if !filepath.IsAbs(specRoot) {
+		specRoot = filepath.Join(cwd, specRoot)
+	}
+
+	files, err := os.ReadDir(specRoot)
+	if err != nil {
+		fmt.Printf("error reading spec dir: %v\n", err)
+		return
+	}
+
+	apis := make([]*apiDef, 0)
+	for _, file := range files {
+		if file.IsDir() || !strings.HasSuffix(file.Name(), ".yaml") {
+			continue
+		}
+
+		api, err := buildAPI(filepath.Join(specRoot, file.Name()))
+		if err != nil {
+			fmt.Printf("error reading spec %s: %v\n", file.Name(), err)
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(cwd, api.Folder, "server.go")); err == nil {
+			api.HandWritten = true
+		}
+
+		base := strings.TrimSuffix(file.Name(), ".yaml")
+		writeTemplate(filepath.Join(cwd, api.Folder, base+".mock.gen.go"), api, mockTemplate)
+		apis = append(apis, api)
+	}
+
+	sort.Slice(apis, func(i, j int) bool { return apis[i].Folder < apis[j].Folder })
+	writeTemplate(filepath.Join(cwd, "servers.gen.go"), apis, serversTemplate)
+}
+
+func buildAPI(path string) (*apiDef, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	document, err := libopenapi.NewDocumentWithConfiguration(raw, &datamodel.DocumentConfiguration{
+		BasePath:            filepath.Dir(path),
+		AllowFileReferences: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	model, err := document.BuildV3Model()
+	if err != nil {
+		return nil, err
+	}
+	spec := model.Model
+
+	base := strings.TrimSuffix(filepath.Base(path), ".yaml")
+	parts := strings.Split(base, ".")
+	api := &apiDef{
+		Package:    parts[len(parts)-1],
+		Folder:     filepath.Join(parts...),
+		ImportPath: ModulePath + "/" + strings.Join(parts, "/"),
+		Alias:      strings.Join(parts, "_"),
+		Version:    parts[len(parts)-1],
+	}
+	if len(spec.Servers) > 0 {
+		uri, err := url.Parse(spec.Servers[0].URL)
+		if err != nil {
+			return nil, err
+		}
+		api.BaseURL = strings.TrimSuffix(uri.Path, "/")
+		api.Provider = api.BaseURL[strings.LastIndex(api.BaseURL, "/")+1:]
+	}
+
+	operations := collectOperations(spec)
+	byPath := map[string]map[string]*operation{}
+	for _, op := range operations {
+		if byPath[op.Path] == nil {
+			byPath[op.Path] = map[string]*operation{}
+		}
+		byPath[op.Path][op.Method] = op
+	}
+
+	used := map[*operation]bool{}
+	for _, op := range operations {
+		if op.Method != "put" || !strings.HasPrefix(op.ID, "CreateOrUpdate") {
+			continue
+		}
+		res, err := buildResource(spec, api, op, byPath)
+		if err != nil {
+			fmt.Printf("%s: %s is mocked as not implemented: %v\n", base, op.ID, err)
+			continue
+		}
+		api.Resources = append(api.Resources, res)
+		for _, resOp := range []*operation{res.List, res.Get, res.Put, res.Delete} {
+			if resOp != nil {
+				used[resOp] = true
+			}
+		}
+	}
+	for _, op := range operations {
+		if !used[op] {
+			api.Others = append(api.Others, op)
+		}
+	}
+	for _, op := range operations {
+		for _, p := range op.PathParams {
+			if strings.HasPrefix(p.Type, "models.") {
+				api.UsesModels = true
+			}
+		}
+	}
+	for _, res := range api.Resources {
+		for _, typ := range []string{res.Model, res.Metadata, res.Status, res.State, res.Condition, res.Iterator, res.ListMeta} {
+			if strings.HasPrefix(typ, "models.") {
+				api.UsesModels = true
+			}
+		}
+		api.UsesSlices = true
+		for _, op := range []*operation{res.Put, res.Delete} {
+			if op != nil && op.PreconditionField != "" {
+				api.UsesPrecondition = true
+			}
+		}
+	}
+	return api, nil
+}
+
+func collectOperations(spec v3high.Document) []*operation {
+	operations := make([]*operation, 0)
+	if spec.Paths == nil || spec.Paths.PathItems == nil {
+		return operations
+	}
+	for path, item := range spec.Paths.PathItems.FromOldest() {
+		for method, op := range item.GetOperations().FromOldest() {
+			if op.OperationId == "" {
+				continue
+			}
+			operations = append(operations, newOperation(path, strings.ToLower(method), item, op))
+		}
+	}
+	return operations
+}
+
+func newOperation(path, method string, item *v3high.PathItem, op *v3high.Operation) *operation {
+	params := slices.Concat(item.Parameters, op.Parameters)
+	byName := map[string]*v3high.Parameter{}
+	result := &operation{ID: upperFirst(op.OperationId), Method: method, Path: path, Query: map[string]bool{}}
+	for _, p := range params {
+		if p.In == "path" {
+			byName[p.Name] = p
+		} else {
+			result.HasParams = true
+		}
+		if p.In == "query" {
+			result.Query[p.Name] = p.Required != nil && *p.Required
+		}
+		if p.In == "header" && strings.EqualFold(p.Name, PreconditionParam) {
+			result.PreconditionField = upperFirst(goName(p.Name))
+		}
+	}
+
+	// Path parameters are passed in the order they appear in the path.
+	for _, segment := range strings.Split(path, "/") {
+		if !strings.HasPrefix(segment, "{") {
+			continue
+		}
+		name := strings.Trim(segment, "{}")
+		typ := "string"
+		if p, ok := byName[name]; ok && p.Schema != nil {
+			typ = schemaType(p.Schema, "string")
+		}
+		result.PathParams = append(result.PathParams, param{Name: goName(name), Type: typ})
+	}
+
+	args := []string{"c *gin.Context"}
+	stubArgs := []string{"c *gin.Context"}
+	for _, p := range result.PathParams {
+		args = append(args, p.Name+" "+p.Type)
+		stubArgs = append(stubArgs, "_"+p.Name+" "+p.Type)
+	}
+	if result.HasParams {
+		args = append(args, "_params "+result.ID+"Params")
+		stubArgs = append(stubArgs, "_params "+result.ID+"Params")
+	}
+	result.Signature = strings.Join(args, ", ")
+	result.StubSignature = strings.Join(stubArgs, ", ")
+	return result
+}
+
+func buildResource(spec v3high.Document, api *apiDef, put *operation, byPath map[string]map[string]*operation) (*resource, error) {
+	segments := strings.Split(strings.Trim(put.Path, "/"), "/")
+	if len(segments) < 2 || !strings.HasPrefix(segments[len(segments)-1], "{") {
+		return nil, fmt.Errorf("path %s does not end with a name", put.Path)
+	}
+
+	ref, collectionRef, err := refLiterals(segments)
+	if err != nil {
+		return nil, err
+	}
+	put.Ref = ref
+	put.Precondition = precondition(put)
+
+	get := byPath[put.Path]["get"]
+	if get == nil {
+		return nil, fmt.Errorf("no get operation for %s", put.Path)
+	}
+	get.Ref = ref
+	modelProxy := responseSchema(spec, get)
+	if modelProxy == nil || modelProxy.GetReference() == "" {
+		return nil, fmt.Errorf("%s does not return a named schema", get.ID)
+	}
+
+	name := strings.TrimPrefix(put.ID, "CreateOrUpdate")
+	collection := segments[len(segments)-2]
+	res := &resource{
+		Name:       name,
+		KindVar:    lowerFirst(name) + "Kind",
+		Field:      lowerFirst(goName(collection)),
+		KindName:   kebabCase(name),
+		Collection: collection,
+		Model:      schemaType(modelProxy, ""),
+		Put:        put,
+		Get:        get,
+	}
+
+	model := modelProxy.Schema()
+	metadata := property(model, "metadata")
+	status := property(model, "status")
+	if metadata == nil || status == nil {
+		return nil, fmt.Errorf("%s has no metadata or status", res.Model)
+	}
+	res.Metadata = schemaType(metadata, "")
+	res.Status = schemaType(status, "")
+	state := property(status.Schema(), "state")
+	conditions := property(status.Schema(), "conditions")
+	if res.Metadata == "" || res.Status == "" || state == nil || conditions == nil {
+		return nil, fmt.Errorf("%s has no named metadata, status, state or conditions", res.Model)
+	}
+	res.State = schemaType(state, "")
+	if conditions.Schema().Items != nil && conditions.Schema().Items.IsA() {
+		res.Condition = schemaType(conditions.Schema().Items.A, "")
+	}
+	if res.State == "" || res.Condition == "" {
+		return nil, fmt.Errorf("%s has no named state or condition type", res.Model)
+	}
+	if api.States == "" {
+		api.States = res.State
+	}
+	res.MetadataGet, res.MetadataSet = metadataStatements(res.Metadata, metadata.Schema())
+	if property(model, "labels") != nil {
+		if requiredProperty(model, "labels") {
+			res.Labels = []string{"return r.Labels"}
+		} else {
+			res.Labels = []string{"if r.Labels == nil {\nreturn nil\n}", "return *r.Labels"}
+		}
+	}
+	if spec := property(model, "spec"); spec != nil {
+		res.References = referencePaths(spec.Schema(), "spec", 0)
+	}
+
+	if del := byPath[put.Path]["delete"]; del != nil {
+		del.Ref = ref
+		del.Precondition = precondition(del)
+		res.Delete = del
+	}
+	if list := byPath["/"+strings.Join(segments[:len(segments)-1], "/")]["get"]; list != nil {
+		if iterator := responseSchema(spec, list); iterator != nil && iterator.GetReference() != "" {
+			if listMeta := property(iterator.Schema(), "metadata"); listMeta != nil {
+				list.Ref = collectionRef
+				res.List = list
+				res.Iterator = schemaType(iterator, "")
+				res.ListMeta = schemaType(listMeta, "")
+				res.ListOptions = listOptions(list)
+				res.ListSkipToken = property(listMeta.Schema(), "skipToken") != nil && !requiredProperty(listMeta.Schema(), "skipToken")
+			}
+		}
+	}
+	return res, nil
+}
+
+// listOptions returns the expression collecting the store.ListOptions from the
+// optional labels, limit and skipToken parameters of a list operation. The
+// handler uses its parameters then.
+func listOptions(list *operation) string {
+	for _, name := range []string{"labels", "limit", "skipToken"} {
+		if required, ok := list.Query[name]; !ok || required {
+			return "store.ListOptions{}"
+		}
+	}
+	list.Signature = strings.Replace(list.Signature, "_params ", "params ", 1)
+	return "store.NewListOptions(params.Labels, params.Limit, params.SkipToken)"
+}
+
+// precondition returns the expression passing the precondition parameter of
+// a CreateOrUpdate or Delete operation to the store. The handler uses its
+// parameters then.
+func precondition(op *operation) string {
+	if op.PreconditionField == "" {
+		return "nil"
+	}
+	op.Signature = strings.Replace(op.Signature, "_params ", "params ", 1)
+	return "precondition.Version(params." + op.PreconditionField + ")"
+}
+
+// refLiterals builds the store.Ref literals addressing the resource and its
+// collection for a path like
+// "v1/tenants/{tenant}/workspaces/{workspace}/networks/{network}/subnets/{name}".
+func refLiterals(segments []string) (string, string, error) {
+	fields := []string{}
+	rest := segments[:len(segments)-2]
+	for len(rest) > 0 && !strings.HasPrefix(rest[0], "tenants") {
+		rest = rest[1:]
+	}
+	if len(rest) < 2 {
+		return "", "", fmt.Errorf("resource is not tenant-scoped")
+	}
+	fields = append(fields, "Tenant: string("+goName(strings.Trim(rest[1], "{}"))+")")
+	rest = rest[2:]
+	if len(rest) >= 2 && rest[0] == "workspaces" {
+		fields = append(fields, "Workspace: string("+goName(strings.Trim(rest[1], "{}"))+")")
+		rest = rest[2:]
+	}
+	if len(rest)%2 != 0 {
+		return "", "", fmt.Errorf("unexpected path segments %s", strings.Join(rest, "/"))
+	}
+	parents := []string{}
+	for i := 0; i < len(rest); i += 2 {
+		parents = append(parents, fmt.Sprintf(`"%s/" + string(%s)`, rest[i], goName(strings.Trim(rest[i+1], "{}"))))
+	}
+	if len(parents) > 0 {
+		fields = append(fields, "Parent: "+strings.Join(parents, ` + "/" + `))
+	}
+	collection := "store.Ref{" + strings.Join(fields, ", ") + "}"
+	fields = append(fields, "Name: string("+goName(strings.Trim(segments[len(segments)-1], "{}"))+")")
+	return "store.Ref{" + strings.Join(fields, ", ") + "}", collection, nil
+}
+
+func metadataStatements(metadataType string, schema *base.Schema) ([]string, []string) {
+	get := []string{}
+	set := []string{}
+	for _, field := range metadataFields {
+		prop := property(schema, field.Property)
+		if prop == nil {
+			continue
+		}
+		goField := upperFirst(field.Property)
+		optional := !requiredProperty(schema, field.Property)
+		typ := schemaType(prop, "")
+		if typ == "" && len(prop.Schema().Enum) > 0 {
+			typ = metadataType + goField
+		}
+
+		value := "m." + field.Field
+		read := "r.Metadata." + goField
+		if optional {
+			read = "*" + read
+		}
+		if field.Kind == "string" {
+			if typ != "" {
+				value = typ + "(" + value + ")"
+			}
+			read = "string(" + read + ")"
+		}
+
+		if optional {
+			get = append(get, fmt.Sprintf("if r.Metadata.%s != nil {\nm.%s = %s\n}", goField, field.Field, read))
+			set = append(set, fmt.Sprintf("%s := %s\nr.Metadata.%s = &%s", lowerFirst(goField), value, goField, lowerFirst(goField)))
+			continue
+		}
+		get = append(get, fmt.Sprintf("m.%s = %s", field.Field, read))
+		set = append(set, fmt.Sprintf("r.Metadata.%s = %s", goField, value))
+	}
+	return get, set
+}
+
+// referenceSchemas are the shared schemas of references to other resources.
+var referenceSchemas = []string{"Reference", "ReferenceURN", "ReferenceObject"}
+
+// referencePaths returns the paths of the reference properties in a schema,
+// like "spec.skuRef" or "spec.nics[].subnetRef".
+func referencePaths(schema *base.Schema, prefix string, depth int) []string {
+	paths := []string{}
+	if schema == nil || depth > 5 {
+		return paths
+	}
+	for _, part := range schema.AllOf {
+		paths = append(paths, referencePaths(part.Schema(), prefix, depth+1)...)
+	}
+	if schema.Properties == nil {
+		return paths
+	}
+	for name, prop := range schema.Properties.FromOldest() {
+		path := prefix + "." + name
+		if isReference(prop) {
+			paths = append(paths, path)
+			continue
+		}
+		propSchema := prop.Schema()
+		if propSchema != nil && propSchema.Items != nil && propSchema.Items.IsA() {
+			path += "[]"
+			if isReference(propSchema.Items.A) {
+				paths = append(paths, path)
+				continue
+			}
+			propSchema = propSchema.Items.A.Schema()
+		}
+		paths = append(paths, referencePaths(propSchema, path, depth+1)...)
+	}
+	return paths
+}
+
+func isReference(proxy *base.SchemaProxy) bool {
+	ref := proxy.GetReference()
+	if ref == "" && proxy.Schema() != nil && len(proxy.Schema().AllOf) == 1 {
+		ref = proxy.Schema().AllOf[0].GetReference()
+	}
+	return ref != "" && slices.Contains(referenceSchemas, refName(ref))
+}
+
+func responseSchema(spec v3high.Document, op *operation) *base.SchemaProxy {
+	item := spec.Paths.PathItems.GetOrZero(op.Path)
+	if item == nil {
+		return nil
+	}
+	operation := item.GetOperations().GetOrZero(op.Method)
+	if operation == nil || operation.Responses == nil || operation.Responses.Codes == nil {
+		return nil
+	}
+	response := operation.Responses.Codes.GetOrZero("200")
+	if response == nil || response.Content == nil {
+		return nil
+	}
+	media := response.Content.GetOrZero("application/json")
+	if media == nil {
+		return nil
+	}
+	return media.Schema
+}
+
+// property looks up a property of a schema, including the schemas it is
+// composed of with allOf.
+func property(schema *base.Schema, name string) *base.SchemaProxy {
+	if schema == nil {
+		return nil
+	}
+	if schema.Properties != nil {
+		if prop, ok := schema.Properties.Get(name); ok {
+			return prop
+		}
+	}
+	for _, part := range schema.AllOf {
+		if prop := property(part.Schema(), name); prop != nil {
+			return prop
+		}
+	}
+	return nil
+}
+
+func requiredProperty(schema *base.Schema, name string) bool {
+	if schema == nil {
+		return false
+	}
+	if slices.Contains(schema.Required, name) {
+		return true
+	}
+	for _, part := range schema.AllOf {
+		if requiredProperty(part.Schema(), name) {
+			return true
+		}
+	}
+	return false
+}
+
+// schemaType returns the Go type oapi-codegen generates for a named schema:
+// schemas of the spec itself live in the API package, shared schemas in the
+// models package. Unnamed schemas get the fallback.
+func schemaType(proxy *base.SchemaProxy, fallback string) string {
+	ref := proxy.GetReference()
+	if ref == "" && proxy.Schema() != nil && len(proxy.Schema().AllOf) == 1 {
+		ref = proxy.Schema().AllOf[0].GetReference()
+	}
+	if ref == "" {
+		return fallback
+	}
+	if strings.HasPrefix(ref, "#") {
+		return refName(ref)
+	}
+	return "models." + refName(ref)
+}
+
+func refName(ref string) string {
+	return ref[strings.LastIndex(ref, "/")+1:]
+}
+
+func goName(s string) string {
+	parts := strings.FieldsFunc(s, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for i := 1; i < len(parts); i++ {
+		parts[i] = upperFirst(parts[i])
+	}
+	return lowerFirst(strings.Join(parts, ""))
+}
+
+func kebabCase(s string) string {
+	var b strings.Builder
+	for i, r := range s {
+		if unicode.IsUpper(r) {
+			if i > 0 {
+				b.WriteByte('-')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+func upperFirst(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
+
+func lowerFirst(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToLower(s[:1]) + s[1:]
+}
+
+func writeTemplate(outPath string, data any, tmpl *template.Template) {
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		fmt.Printf("error executing template for %s: %v\n", outPath, err)
+		return
+	}
+
+	formatted, err := format.Source(buf.Bytes())
+	if err != nil {
+		fmt.Printf("error formatting %s: %v\n", outPath, err)
+		formatted = buf.Bytes()
+	}
+
+	if err := os.WriteFile(outPath, formatted, 0o644); err != nil {
+		fmt.Printf("error writing %s: %v\n", outPath, err)
+	}
+}
@@ -0,0 +1,173 @@
+package admin
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	"cape-project.eu/mockserver/internal/registry"
+	"github.com/gin-gonic/gin"
+	"go.yaml.in/yaml/v4"
+)
+
+// BasePath is where the admin API is served.
+const BasePath = "/_admin"
+
+// Reset removes everything belonging to the tenant, or everything if tenant is
+// empty.
+func Reset(tenant string) {
+	for _, c := range registry.Collections() {
+		c.Reset(tenant)
+	}
+}
+
+// Dump returns the content of all collections by their name.
+func Dump() map[string]any {
+	state := map[string]any{}
+	for _, c := range registry.Collections() {
+		if content := c.Dump(); content != nil {
+			state[c.Name()] = content
+		}
+	}
+	return state
+}
+
+// fixture holds the fields of a resource that tell which collection it is
+// seeded into.
+type fixture struct {
+	Metadata struct {
+		Kind     string `json:"kind"`
+		Provider string `json:"provider"`
+		Name     string `json:"name"`
+	} `json:"metadata"`
+}
+
+// Seed adds fixtures, given as YAML or JSON list of resources like the API
+// returns them or an object with a "resources" list. The collection of each
+// resource is chosen by its metadata kind and provider.
+func Seed(raw []byte) (int, error) {
+	var resources []any
+	if err := yaml.Unmarshal(raw, &resources); err != nil {
+		var file struct {
+			Resources []any `yaml:"resources"`
+		}
+		if err := yaml.Unmarshal(raw, &file); err != nil {
+			return 0, fmt.Errorf("parsing fixtures: %w", err)
+		}
+		resources = file.Resources
+	}
+
+	for i, resource := range resources {
+		encoded, err := json.Marshal(resource)
+		if err != nil {
+			return i, fmt.Errorf("fixture %d: %w", i, err)
+		}
+		var f fixture
+		if err := json.Unmarshal(encoded, &f); err != nil {
+			return i, fmt.Errorf("fixture %d: %w", i, err)
+		}
+		if f.Metadata.Kind == "" {
+			return i, fmt.Errorf("fixture %d has no metadata.kind", i)
+		}
+		if err := seed(f.Metadata.Provider, f.Metadata.Kind, encoded); err != nil {
+			return i, fmt.Errorf("fixture %d (%s %s): %w", i, f.Metadata.Kind, f.Metadata.Name, err)
+		}
+	}
+	return len(resources), nil
+}
+
+func seed(provider, kind string, raw json.RawMessage) error {
+	for _, c := range registry.Collections() {
+		ok, err := c.Seed(provider, kind, raw)
+		if ok || err != nil {
+			return err
+		}
+	}
+	if provider != "" {
+		return fmt.Errorf("no collection for kind %s of provider %s", kind, provider)
+	}
+	return fmt.Errorf("no collection for kind %s", kind)
+}
+
+// SeedFile adds the fixtures of a YAML or JSON file, see Seed.
+func SeedFile(path string) (int, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	return Seed(raw)
+}
+
+// ErrNotFound is returned by ForceState for unknown resources.
+var ErrNotFound = errors.New("resource not found")
+
+// ForceState moves a resource, given by its path like
+// "tenants/t/workspaces/w/block-storages/b", into a state.
+func ForceState(resource, state string) error {
+	for _, c := range registry.Collections() {
+		ok, err := c.ForceState(resource, state)
+		if ok || err != nil {
+			return err
+		}
+	}
+	return ErrNotFound
+}
+
+// RegisterServer serves the admin API:
+//
+//	GET    /_admin/state                 dumps the state
+//	DELETE /_admin/state?tenant=<tenant> resets all or a tenant's state
+//	POST   /_admin/fixtures              seeds YAML or JSON fixtures
+//	PUT    /_admin/state/<resource path> forces a resource into {"state": ...}
+func RegisterServer(router gin.IRouter) {
+	group := router.Group(BasePath)
+
+	group.GET("/state", func(c *gin.Context) {
+		c.JSON(http.StatusOK, Dump())
+	})
+
+	group.DELETE("/state", func(c *gin.Context) {
+		tenant := c.Query("tenant")
+		Reset(tenant)
+		c.JSON(http.StatusOK, gin.H{"reset": true, "tenant": tenant})
+	})
+
+	group.POST("/fixtures", func(c *gin.Context) {
+		raw, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		count, err := Seed(raw)
+		if err != nil {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error(), "seeded": count})
+			return
+		}
+		c.JSON(http.StatusCreated, gin.H{"seeded": count})
+	})
+
+	group.PUT("/state/*resource", func(c *gin.Context) {
+		var body struct {
+			State string `json:"state" binding:"required"`
+		}
+		if err := c.ShouldBindJSON(&body); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		resource := strings.Trim(c.Param("resource"), "/")
+		if err := ForceState(resource, body.State); err != nil {
+			status := http.StatusBadRequest
+			if errors.Is(err, ErrNotFound) {
+				status = http.StatusNotFound
+			}
+			c.JSON(status, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"resource": resource, "state": body.State})
+	})
+}
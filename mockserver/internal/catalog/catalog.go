@@ -0,0 +1,136 @@
+package catalog
+
+import (
+	"encoding/json"
+	"strings"
+	"sync"
+
+	"cape-project.eu/mockserver/internal/registry"
+)
+
+// Catalog is a list of definitions an API serves read-only, like its SKUs.
+// Fixtures seeded through the admin API add to it or replace definitions of
+// the same name.
+type Catalog[D any] struct {
+	provider   string
+	kind       string
+	collection string
+	defaults   []D
+	name       func(def D) string
+	parse      func(raw json.RawMessage) (D, error)
+
+	mu    sync.RWMutex
+	items []D
+}
+
+// New creates a catalog serving the defaults under
+// "tenants/<tenant>/<collection>/<name>" for every tenant and registers it
+// with the registry. Fixtures of the given provider and kind are converted
+// with parse.
+func New[D any](provider, kind, collection string, defaults []D, name func(def D) string, parse func(raw json.RawMessage) (D, error)) *Catalog[D] {
+	c := &Catalog[D]{
+		provider:   provider,
+		kind:       kind,
+		collection: collection,
+		defaults:   defaults,
+		name:       name,
+		parse:      parse,
+		items:      append([]D(nil), defaults...),
+	}
+	registry.Register(c)
+	return c
+}
+
+// All returns the definitions in the catalog.
+func (c *Catalog[D]) All() []D {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return append([]D(nil), c.items...)
+}
+
+// Get returns the definition with the given name.
+func (c *Catalog[D]) Get(name string) (D, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	for _, def := range c.items {
+		if c.name(def) == name {
+			return def, true
+		}
+	}
+	var zero D
+	return zero, false
+}
+
+// Add adds a definition, replacing the one with the same name.
+func (c *Catalog[D]) Add(def D) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for i, existing := range c.items {
+		if c.name(existing) == c.name(def) {
+			c.items[i] = def
+			return
+		}
+	}
+	c.items = append(c.items, def)
+}
+
+func (c *Catalog[D]) Name() string {
+	return c.provider + "/" + c.collection
+}
+
+func (c *Catalog[D]) Provider() string {
+	return c.provider
+}
+
+// State finds definitions by their path in any tenant. They have no state.
+func (c *Catalog[D]) State(resource string) (string, bool) {
+	segments := strings.Split(resource, "/")
+	if len(segments) != 4 || segments[0] != "tenants" || segments[2] != c.collection {
+		return "", false
+	}
+	_, ok := c.Get(segments[3])
+	return "", ok
+}
+
+// Count returns zero, nothing is nested in definitions.
+func (c *Catalog[D]) Count(string) int {
+	return 0
+}
+
+// Reset restores the defaults when all state is reset. Catalogs are shared by
+// all tenants, so resetting a single tenant leaves them alone.
+func (c *Catalog[D]) Reset(tenant string) {
+	if tenant != "" {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.items = append([]D(nil), c.defaults...)
+}
+
+// Dump returns nothing, catalogs are configuration rather than state.
+func (c *Catalog[D]) Dump() any {
+	return nil
+}
+
+func (c *Catalog[D]) Seed(provider, kind string, raw json.RawMessage) (bool, error) {
+	// SKUs name their provider with version, e.g. "seca.storage/v1".
+	if provider, _, _ = strings.Cut(provider, "/"); kind != c.kind || provider != "" && provider != c.provider {
+		return false, nil
+	}
+	def, err := c.parse(raw)
+	if err != nil {
+		return true, err
+	}
+	c.Add(def)
+	return true, nil
+}
+
+func (c *Catalog[D]) ForceState(string, string) (bool, error) {
+	return false, nil
+}
@@ -0,0 +1,307 @@
+package fault
+
+import (
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"cape-project.eu/mockserver/internal/admin"
+	"github.com/gin-gonic/gin"
+	"go.yaml.in/yaml/v4"
+)
+
+// Duration is a time.Duration written like "500ms" in rules.
+type Duration time.Duration
+
+func (d *Duration) UnmarshalText(text []byte) error {
+	parsed, err := time.ParseDuration(string(text))
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(time.Duration(d).String()), nil
+}
+
+// Rule injects faults into the requests and resources it matches. Empty
+// match fields match everything.
+type Rule struct {
+	// Method and Path match requests; Path is a prefix like
+	// "/providers/seca.compute/v1/tenants/t/workspaces/w/instances".
+	Method string `yaml:"method,omitempty" json:"method,omitempty"`
+	Path   string `yaml:"path,omitempty" json:"path,omitempty"`
+	// Provider, Kind and Tenant match requests and resources. Provider is
+	// like "seca.compute", Kind is either the kind like "block-storage" or
+	// the collection like "block-storages".
+	Provider string `yaml:"provider,omitempty" json:"provider,omitempty"`
+	Kind     string `yaml:"kind,omitempty" json:"kind,omitempty"`
+	Tenant   string `yaml:"tenant,omitempty" json:"tenant,omitempty"`
+
+	// Probability of the rule applying to a match, always if zero.
+	Probability float64 `yaml:"probability,omitempty" json:"probability,omitempty"`
+	// Times limits how often the rule applies, unlimited if zero.
+	Times int `yaml:"times,omitempty" json:"times,omitempty"`
+
+	// Latency delays matched requests.
+	Latency Duration `yaml:"latency,omitempty" json:"latency,omitempty"`
+	// Status answers matched requests with an error status like 429 or 503
+	// instead of handling them.
+	Status int `yaml:"status,omitempty" json:"status,omitempty"`
+	// RetryAfter is sent along with the status, in seconds.
+	RetryAfter int `yaml:"retryAfter,omitempty" json:"retryAfter,omitempty"`
+	// Drop closes the connection of matched requests without answering.
+	Drop bool `yaml:"drop,omitempty" json:"drop,omitempty"`
+
+	// TransitionDelay slows down the state transitions of matched resources.
+	TransitionDelay Duration `yaml:"transitionDelay,omitempty" json:"transitionDelay,omitempty"`
+	// ErrorState lets matched resources end in the error state instead of
+	// becoming active.
+	ErrorState bool `yaml:"errorState,omitempty" json:"errorState,omitempty"`
+
+	applied int
+}
+
+func (r *Rule) affectsRequests() bool {
+	return r.Latency > 0 || r.Status != 0 || r.Drop
+}
+
+func (r *Rule) affectsLifecycle() bool {
+	return r.TransitionDelay > 0 || r.ErrorState
+}
+
+func (r *Rule) matches(provider, kind, collection, tenant string) bool {
+	return (r.Provider == "" || r.Provider == provider) &&
+		(r.Kind == "" || r.Kind == kind || r.Kind == collection) &&
+		(r.Tenant == "" || r.Tenant == tenant)
+}
+
+// apply reports whether the rule applies this time, counting it if so. It
+// must be called with the lock held.
+func (r *Rule) apply() bool {
+	if r.Times > 0 && r.applied >= r.Times {
+		return false
+	}
+	if r.Probability > 0 && rand.Float64() >= r.Probability {
+		return false
+	}
+	r.applied++
+	return true
+}
+
+var (
+	mu    sync.Mutex
+	rules []*Rule
+)
+
+// Parse reads rules from YAML or JSON, either a list of rules or an object
+// with a "rules" list.
+func Parse(raw []byte) ([]Rule, error) {
+	var parsed []Rule
+	if err := yaml.Unmarshal(raw, &parsed); err != nil {
+		var file struct {
+			Rules []Rule `yaml:"rules"`
+		}
+		if err := yaml.Unmarshal(raw, &file); err != nil {
+			return nil, fmt.Errorf("parsing fault rules: %w", err)
+		}
+		parsed = file.Rules
+	}
+	for i, rule := range parsed {
+		if rule.Status != 0 && (rule.Status < 400 || rule.Status > 599) {
+			return nil, fmt.Errorf("rule %d: status %d is not an error status", i, rule.Status)
+		}
+		if rule.Probability < 0 || rule.Probability > 1 {
+			return nil, fmt.Errorf("rule %d: probability must be between 0 and 1", i)
+		}
+		if !rule.affectsRequests() && !rule.affectsLifecycle() {
+			return nil, fmt.Errorf("rule %d injects no fault", i)
+		}
+	}
+	return parsed, nil
+}
+
+// Load reads rules from a YAML or JSON file, see Parse.
+func Load(path string) ([]Rule, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(raw)
+}
+
+// Configure replaces the active rules.
+func Configure(configured []Rule) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	rules = make([]*Rule, len(configured))
+	for i := range configured {
+		rule := configured[i]
+		rule.applied = 0
+		rules[i] = &rule
+	}
+}
+
+// Rules returns the active rules.
+func Rules() []Rule {
+	mu.Lock()
+	defer mu.Unlock()
+
+	active := make([]Rule, len(rules))
+	for i, rule := range rules {
+		active[i] = *rule
+	}
+	return active
+}
+
+// Transition is what happens to the state transitions of a resource.
+type Transition struct {
+	Delay time.Duration
+	Fail  bool
+}
+
+// Lifecycle returns the faults for the state transitions of a resource that
+// was just written. The first matching rule that affects the lifecycle
+// applies.
+func Lifecycle(provider, kind, collection, tenant string) Transition {
+	mu.Lock()
+	defer mu.Unlock()
+
+	for _, rule := range rules {
+		if rule.affectsLifecycle() && rule.matches(provider, kind, collection, tenant) && rule.apply() {
+			return Transition{Delay: time.Duration(rule.TransitionDelay), Fail: rule.ErrorState}
+		}
+	}
+	return Transition{}
+}
+
+// Inject applies the first matching rule that affects requests to every
+// request of the provider APIs.
+func Inject() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		rule, ok := requestRule(c.Request.Method, c.Request.URL.Path)
+		if !ok {
+			c.Next()
+			return
+		}
+
+		if rule.Latency > 0 {
+			select {
+			case <-time.After(time.Duration(rule.Latency)):
+			case <-c.Request.Context().Done():
+				c.Abort()
+				return
+			}
+		}
+		if rule.Drop {
+			drop(c)
+			return
+		}
+		if rule.Status != 0 {
+			if rule.RetryAfter > 0 {
+				c.Header("Retry-After", strconv.Itoa(rule.RetryAfter))
+			}
+			c.AbortWithStatusJSON(rule.Status, gin.H{"error": "injected fault: " + strings.ToLower(http.StatusText(rule.Status))})
+			return
+		}
+		c.Next()
+	}
+}
+
+func requestRule(method, path string) (Rule, bool) {
+	provider, collection, tenant, ok := parsePath(path)
+	if !ok {
+		return Rule{}, false
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	for _, rule := range rules {
+		if !rule.affectsRequests() || !rule.matches(provider, "", collection, tenant) {
+			continue
+		}
+		if rule.Method != "" && !strings.EqualFold(rule.Method, method) || !strings.HasPrefix(path, rule.Path) {
+			continue
+		}
+		if rule.apply() {
+			return *rule, true
+		}
+	}
+	return Rule{}, false
+}
+
+// parsePath extracts provider, collection and tenant of a provider API path
+// like "/providers/seca.network/v1/tenants/t/workspaces/w/networks/n". The
+// collection is the one addressed last.
+func parsePath(path string) (string, string, string, bool) {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	if len(segments) < 3 || segments[0] != "providers" {
+		return "", "", "", false
+	}
+	provider := segments[1]
+	segments = segments[3:]
+
+	var tenant string
+	if len(segments) >= 2 && segments[0] == "tenants" {
+		tenant = segments[1]
+	}
+	var collection string
+	for i := 0; i < len(segments); i += 2 {
+		collection = segments[i]
+	}
+	return provider, collection, tenant, true
+}
+
+// drop closes the connection without sending a response.
+func drop(c *gin.Context) {
+	c.Abort()
+	conn, _, err := c.Writer.Hijack()
+	if err != nil {
+		c.Status(http.StatusServiceUnavailable)
+		return
+	}
+	conn.Close()
+}
+
+// RegisterServer adds the fault rules to the admin API:
+//
+//	GET    /_admin/faults returns the active rules
+//	PUT    /_admin/faults replaces them with YAML or JSON rules
+//	DELETE /_admin/faults removes all rules
+func RegisterServer(router gin.IRouter) {
+	group := router.Group(admin.BasePath + "/faults")
+
+	group.GET("", func(c *gin.Context) {
+		c.JSON(http.StatusOK, Rules())
+	})
+
+	group.PUT("", func(c *gin.Context) {
+		raw, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		parsed, err := Parse(raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		Configure(parsed)
+		c.JSON(http.StatusOK, Rules())
+	})
+
+	group.DELETE("", func(c *gin.Context) {
+		Configure(nil)
+		c.JSON(http.StatusOK, Rules())
+	})
+}
@@ -0,0 +1,154 @@
+package fault
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		raw     string
+		want    int
+		wantErr string
+	}{
+		{name: "list", raw: "- status: 503\n- latency: 500ms\n  provider: seca.compute", want: 2},
+		{name: "rules object", raw: "rules:\n  - status: 429\n    retryAfter: 2\n  - transitionDelay: 1s", want: 2},
+		{name: "json", raw: `{"rules": [{"kind": "instances", "errorState": true}]}`, want: 1},
+		{name: "no rules", raw: "rules: []", want: 0},
+		{name: "not an error status", raw: "- status: 200", wantErr: "status 200 is not an error status"},
+		{name: "probability out of range", raw: "- status: 503\n  probability: 1.5", wantErr: "probability must be between 0 and 1"},
+		{name: "no fault", raw: "- provider: seca.compute", wantErr: "rule 0 injects no fault"},
+		{name: "invalid duration", raw: "- latency: soon", wantErr: "parsing fault rules"},
+		{name: "invalid document", raw: "rules: 5", wantErr: "parsing fault rules"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parsed, err := Parse([]byte(tt.raw))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Parse() = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(parsed) != tt.want {
+				t.Errorf("Parse() = %d rules, want %d", len(parsed), tt.want)
+			}
+		})
+	}
+
+	parsed, err := Parse([]byte("- latency: 1500ms\n  retryAfter: 3\n  status: 429"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed[0].Latency != Duration(1500*time.Millisecond) || parsed[0].Status != 429 || parsed[0].RetryAfter != 3 {
+		t.Errorf("Parse() = %+v", parsed[0])
+	}
+}
+
+func TestRequestRule(t *testing.T) {
+	const instances = "/providers/seca.compute/v1/tenants/t1/workspaces/w/instances/i"
+
+	tests := []struct {
+		name   string
+		rule   Rule
+		method string
+		path   string
+		want   bool
+	}{
+		{name: "any request", rule: Rule{Status: 503}, method: "GET", path: instances, want: true},
+		{name: "method", rule: Rule{Status: 503, Method: "put"}, method: "PUT", path: instances, want: true},
+		{name: "other method", rule: Rule{Status: 503, Method: "DELETE"}, method: "PUT", path: instances},
+		{name: "path prefix", rule: Rule{Status: 503, Path: "/providers/seca.compute/v1/tenants/t1"}, method: "GET", path: instances, want: true},
+		{name: "other path", rule: Rule{Status: 503, Path: "/providers/seca.compute/v1/tenants/t2"}, method: "GET", path: instances},
+		{name: "provider", rule: Rule{Status: 503, Provider: "seca.compute"}, method: "GET", path: instances, want: true},
+		{name: "other provider", rule: Rule{Status: 503, Provider: "seca.storage"}, method: "GET", path: instances},
+		{name: "collection", rule: Rule{Status: 503, Kind: "instances"}, method: "GET", path: instances, want: true},
+		{name: "collection of a list", rule: Rule{Status: 503, Kind: "instances"}, method: "GET", path: "/providers/seca.compute/v1/tenants/t1/workspaces/w/instances", want: true},
+		{name: "other collection", rule: Rule{Status: 503, Kind: "workspaces"}, method: "GET", path: instances},
+		{name: "tenant", rule: Rule{Status: 503, Tenant: "t1"}, method: "GET", path: instances, want: true},
+		{name: "other tenant", rule: Rule{Status: 503, Tenant: "t2"}, method: "GET", path: instances},
+		{name: "lifecycle rule", rule: Rule{ErrorState: true}, method: "GET", path: instances},
+		{name: "outside the provider APIs", rule: Rule{Status: 503}, method: "GET", path: "/_admin/state"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			Configure([]Rule{tt.rule})
+			t.Cleanup(func() { Configure(nil) })
+
+			if _, got := requestRule(tt.method, tt.path); got != tt.want {
+				t.Errorf("requestRule(%s %s) = %v, want %v", tt.method, tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRequestRuleOrderAndTimes(t *testing.T) {
+	Configure([]Rule{{Status: 429, Times: 2}, {Status: 503}})
+	t.Cleanup(func() { Configure(nil) })
+
+	const path = "/providers/seca.storage/v1/tenants/t/workspaces/w/block-storages"
+	for i, want := range []int{429, 429, 503, 503} {
+		rule, ok := requestRule("GET", path)
+		if !ok || rule.Status != want {
+			t.Errorf("request %d: rule = %+v, %v, want status %d", i, rule, ok, want)
+		}
+	}
+	if applied := Rules()[0].applied; applied != 2 {
+		t.Errorf("first rule applied %d times, want 2", applied)
+	}
+
+	Configure(Rules())
+	if rule, _ := requestRule("GET", path); rule.Status != 429 {
+		t.Errorf("rule = %+v after reconfiguring, want the counts reset", rule)
+	}
+}
+
+func TestLifecycle(t *testing.T) {
+	Configure([]Rule{
+		{Status: 503},
+		{Kind: "block-storage", Tenant: "t", TransitionDelay: Duration(time.Second)},
+		{Provider: "seca.compute", ErrorState: true},
+	})
+	t.Cleanup(func() { Configure(nil) })
+
+	tests := []struct {
+		provider, kind, collection, tenant string
+		want                               Transition
+	}{
+		{"seca.storage", "block-storage", "block-storages", "t", Transition{Delay: time.Second}},
+		{"seca.storage", "block-storage", "block-storages", "other", Transition{}},
+		{"seca.compute", "instance", "instances", "t", Transition{Fail: true}},
+		{"seca.network", "network", "networks", "t", Transition{}},
+	}
+	for _, tt := range tests {
+		if got := Lifecycle(tt.provider, tt.kind, tt.collection, tt.tenant); got != tt.want {
+			t.Errorf("Lifecycle(%s, %s, %s) = %+v, want %+v", tt.provider, tt.kind, tt.tenant, got, tt.want)
+		}
+	}
+}
+
+func TestParsePath(t *testing.T) {
+	tests := []struct {
+		path                         string
+		provider, collection, tenant string
+		ok                           bool
+	}{
+		{"/providers/seca.network/v1/tenants/t/workspaces/w/networks/n", "seca.network", "networks", "t", true},
+		{"/providers/seca.network/v1/tenants/t/workspaces/w/networks/n/subnets", "seca.network", "subnets", "t", true},
+		{"/providers/seca.compute/v1/tenants/t/skus", "seca.compute", "skus", "t", true},
+		{"/providers/seca.region/v1/regions", "seca.region", "regions", "", true},
+		{"/providers/seca.region", "", "", "", false},
+		{"/_admin/faults", "", "", "", false},
+	}
+	for _, tt := range tests {
+		provider, collection, tenant, ok := parsePath(tt.path)
+		if provider != tt.provider || collection != tt.collection || tenant != tt.tenant || ok != tt.ok {
+			t.Errorf("parsePath(%s) = %s, %s, %s, %v", tt.path, provider, collection, tenant, ok)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package labels
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// MatchSelector reports whether labels satisfy a selector like
+// "tier=RD*,iops>=500": a comma-separated list of filters that all have to
+// match. Keys and values of = and != filters may contain * wildcards, the
+// other operators compare numerically.
+func MatchSelector[L ~map[string]string](labels L, selector string) bool {
+	selector = strings.TrimSpace(selector)
+	if selector == "" {
+		return true
+	}
+
+	filters := strings.SplitSeq(selector, ",")
+	for filter := range filters {
+		if !matchesFilter(labels, strings.TrimSpace(filter)) {
+			return false
+		}
+	}
+	return true
+}
+
+func matchesFilter[L ~map[string]string](labels L, filter string) bool {
+	if filter == "" {
+		return true
+	}
+
+	operators := []string{"!=", ">=", "<=", "=", ">", "<"}
+	for _, operator := range operators {
+		if !strings.Contains(filter, operator) {
+			continue
+		}
+
+		parts := strings.SplitN(filter, operator, 2)
+		if len(parts) != 2 {
+			return false
+		}
+
+		keyPattern := strings.TrimSpace(parts[0])
+		valuePattern := strings.TrimSpace(parts[1])
+		if keyPattern == "" {
+			return false
+		}
+
+		switch operator {
+		case "=":
+			for key, value := range labels {
+				if matchPattern(keyPattern, key) && matchPattern(valuePattern, value) {
+					return true
+				}
+			}
+			return false
+		case "!=":
+			for key, value := range labels {
+				if matchPattern(keyPattern, key) && matchPattern(valuePattern, value) {
+					return false
+				}
+			}
+			return true
+		default:
+			target, err := strconv.ParseFloat(valuePattern, 64)
+			if err != nil {
+				return false
+			}
+			for key, value := range labels {
+				if !matchPattern(keyPattern, key) {
+					continue
+				}
+				current, err := strconv.ParseFloat(value, 64)
+				if err != nil {
+					continue
+				}
+				switch operator {
+				case ">":
+					if current > target {
+						return true
+					}
+				case "<":
+					if current < target {
+						return true
+					}
+				case ">=":
+					if current >= target {
+						return true
+					}
+				case "<=":
+					if current <= target {
+						return true
+					}
+				}
+			}
+			return false
+		}
+	}
+
+	return false
+}
+
+func matchPattern(pattern string, value string) bool {
+	if strings.Contains(pattern, "*") {
+		regex := "^" + strings.ReplaceAll(regexp.QuoteMeta(pattern), "\\*", ".*") + "$"
+		re, err := regexp.Compile(regex)
+		if err != nil {
+			return false
+		}
+		return re.MatchString(value)
+	}
+	return value == pattern
+}
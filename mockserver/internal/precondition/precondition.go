@@ -0,0 +1,36 @@
+package precondition
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Version converts the precondition parameter of a CreateOrUpdate or Delete
+// request to the resource version it expects, or nil if it is not given.
+func Version[V ~int | ~int32 | ~int64](param *V) *int64 {
+	if param == nil {
+		return nil
+	}
+	version := int64(*param)
+	return &version
+}
+
+// Holds reports whether a resource in the given version meets the expected
+// version of a request, if any. It answers the request with 412 if not; a
+// missing resource never meets a precondition.
+func Holds(c *gin.Context, expected *int64, exists bool, version int64) bool {
+	if expected == nil {
+		return true
+	}
+	if !exists {
+		c.JSON(http.StatusPreconditionFailed, gin.H{"error": "resource does not exist"})
+		return false
+	}
+	if *expected != version {
+		c.JSON(http.StatusPreconditionFailed, gin.H{"error": fmt.Sprintf("resource version is %d, not %d", version, *expected)})
+		return false
+	}
+	return true
+}
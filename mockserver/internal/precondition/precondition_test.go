@@ -0,0 +1,51 @@
+package precondition
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestVersion(t *testing.T) {
+	if got := Version[int](nil); got != nil {
+		t.Errorf("Version(nil) = %d, want nil", *got)
+	}
+	param := 7
+	if got := Version(&param); got == nil || *got != 7 {
+		t.Errorf("Version(7) = %v, want 7", got)
+	}
+}
+
+func TestHolds(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	version := func(v int64) *int64 { return &v }
+
+	tests := []struct {
+		name     string
+		expected *int64
+		exists   bool
+		want     bool
+		status   int
+	}{
+		{name: "no precondition", exists: true, want: true},
+		{name: "no precondition on create", want: true},
+		{name: "matching version", expected: version(3), exists: true, want: true},
+		{name: "stale version", expected: version(2), exists: true, status: http.StatusPreconditionFailed},
+		{name: "missing resource", expected: version(3), status: http.StatusPreconditionFailed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+
+			if got := Holds(c, tt.expected, tt.exists, 3); got != tt.want {
+				t.Fatalf("Holds() = %v, want %v", got, tt.want)
+			}
+			if !tt.want && w.Code != tt.status {
+				t.Errorf("status = %d, want %d", w.Code, tt.status)
+			}
+		})
+	}
+}
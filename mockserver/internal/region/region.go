@@ -0,0 +1,104 @@
+package region
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+
+	"go.yaml.in/yaml/v4"
+)
+
+// Provider is an API offered in a region, reachable under URL.
+type Provider struct {
+	Name    string `yaml:"name" json:"name"`
+	URL     string `yaml:"url" json:"url"`
+	Version string `yaml:"version" json:"version"`
+}
+
+// Region is an entry of the region catalog.
+type Region struct {
+	Name      string     `yaml:"name" json:"name"`
+	Zones     []string   `yaml:"zones" json:"zones"`
+	Providers []Provider `yaml:"providers" json:"providers"`
+}
+
+// DefaultCatalog is served unless a catalog file is configured. It offers
+// every API the mockserver implements.
+var DefaultCatalog = []Region{
+	{
+		Name:  "eu-central-1",
+		Zones: []string{"eu-central-1a", "eu-central-1b", "eu-central-1c"},
+		Providers: []Provider{
+			{Name: "seca.authorization", URL: "/providers/seca.authorization", Version: "v1"},
+			{Name: "seca.compute", URL: "/providers/seca.compute", Version: "v1"},
+			{Name: "seca.kubernetes", URL: "/providers/seca.kubernetes", Version: "v1beta1"},
+			{Name: "seca.loadbalancer", URL: "/providers/seca.loadbalancer", Version: "v1beta1"},
+			{Name: "seca.natgateway", URL: "/providers/seca.natgateway", Version: "v1beta1"},
+			{Name: "seca.network", URL: "/providers/seca.network", Version: "v1"},
+			{Name: "seca.objectstorage", URL: "/providers/seca.objectstorage", Version: "v1beta1"},
+			{Name: "seca.region", URL: "/providers/seca.region", Version: "v1"},
+			{Name: "seca.storage", URL: "/providers/seca.storage", Version: "v1"},
+			{Name: "seca.workspace", URL: "/providers/seca.workspace", Version: "v1"},
+		},
+	},
+}
+
+var (
+	mu      sync.RWMutex
+	catalog = DefaultCatalog
+)
+
+// Load reads a catalog from a YAML or JSON file, either a list of regions or
+// an object with a "regions" list.
+func Load(path string) ([]Region, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var regions []Region
+	if err := yaml.Unmarshal(raw, &regions); err != nil {
+		var file struct {
+			Regions []Region `yaml:"regions"`
+		}
+		if err := yaml.Unmarshal(raw, &file); err != nil {
+			return nil, fmt.Errorf("parsing region catalog %s: %w", path, err)
+		}
+		regions = file.Regions
+	}
+	if len(regions) == 0 {
+		return nil, errors.New("region catalog " + path + " is empty")
+	}
+	for i, region := range regions {
+		if region.Name == "" {
+			return nil, fmt.Errorf("region %d of catalog %s has no name", i, path)
+		}
+	}
+	return regions, nil
+}
+
+// Configure replaces the catalog served by the region API.
+func Configure(regions []Region) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	catalog = regions
+}
+
+// Catalog returns all configured regions.
+func Catalog() []Region {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	return catalog
+}
+
+// Default is the region all mocked resources live in: the first region of
+// the catalog.
+func Default() string {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	return catalog[0].Name
+}
@@ -0,0 +1,149 @@
+package registry
+
+import (
+	"encoding/json"
+	"strings"
+	"sync"
+)
+
+// Collection is a part of the mockserver state shared across the servers,
+// like the resources of one kind or the SKUs of an API. It is used to resolve
+// references between resources and by the admin API.
+type Collection interface {
+	// Name identifies the collection, e.g. "seca.storage/v1/block-storages".
+	Name() string
+	// Provider is the API the collection belongs to, e.g. "seca.storage".
+	Provider() string
+	// State returns the state of the resource with the given path, e.g.
+	// "tenants/t/workspaces/w/block-storages/b".
+	State(resource string) (state string, ok bool)
+	// Count returns the number of resources whose path starts with prefix.
+	Count(prefix string) int
+
+	// Reset removes everything belonging to the tenant, or everything if
+	// tenant is empty.
+	Reset(tenant string)
+	// Dump returns the content of the collection, or nil if there is nothing
+	// worth dumping.
+	Dump() any
+	// Seed adds a fixture if it is of the given provider and kind. It reports
+	// whether the fixture was meant for this collection.
+	Seed(provider, kind string, raw json.RawMessage) (bool, error)
+	// ForceState moves the resource with the given path into a state. It
+	// reports whether the resource is in this collection.
+	ForceState(resource, state string) (bool, error)
+}
+
+var (
+	mu          sync.RWMutex
+	collections []Collection
+)
+
+// Register shares a collection with the other servers.
+func Register(c Collection) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	collections = append(collections, c)
+}
+
+// Collections returns all registered collections.
+func Collections() []Collection {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	return collections
+}
+
+// Lookup returns the state of the resource with the given path in the
+// collections of a provider, or of all providers if provider is empty.
+func Lookup(provider, resource string) (string, bool) {
+	provider, _, _ = strings.Cut(provider, "/")
+	for _, c := range Collections() {
+		if provider != "" && c.Provider() != provider {
+			continue
+		}
+		if state, ok := c.State(resource); ok {
+			return state, true
+		}
+	}
+	return "", false
+}
+
+// Children returns the number of resources nested in the resource with the
+// given path, like the resources of a workspace.
+func Children(resource string) int {
+	count := 0
+	for _, c := range Collections() {
+		count += c.Count(resource + "/")
+	}
+	return count
+}
+
+// Reference is a decoded Reference or ReferenceURN of the SecAPI. Empty
+// fields are taken from the referring resource.
+type Reference struct {
+	Provider  string `json:"provider"`
+	Tenant    string `json:"tenant"`
+	Workspace string `json:"workspace"`
+	Resource  string `json:"resource"`
+}
+
+// ParseReference decodes a reference, given either as object or as URN like
+// "seca.storage/v1/tenants/t/skus/seca.rd100" or "skus/seca.rd100".
+func ParseReference(value any) (Reference, bool) {
+	switch value := value.(type) {
+	case string:
+		if i := strings.Index(value, "tenants/"); i > 0 {
+			provider, _, _ := strings.Cut(value[:i], "/")
+			return Reference{Provider: provider, Resource: value[i:]}, true
+		}
+		return Reference{Resource: value}, value != ""
+	case map[string]any:
+		raw, err := json.Marshal(value)
+		if err != nil {
+			return Reference{}, false
+		}
+		var ref Reference
+		if err := json.Unmarshal(raw, &ref); err != nil || ref.Resource == "" {
+			return Reference{}, false
+		}
+		return ref, true
+	}
+	return Reference{}, false
+}
+
+// Resolve returns the path and state of the resource a reference points to.
+// The referring resource's tenant, workspace and provider fill in what the
+// reference leaves open; resources of the same provider are preferred.
+func Resolve(from Reference, ref Reference) (string, string, bool) {
+	if ref.Tenant == "" {
+		ref.Tenant = from.Tenant
+	}
+	if ref.Workspace == "" {
+		ref.Workspace = from.Workspace
+	}
+
+	resource := strings.Trim(ref.Resource, "/")
+	candidates := []string{resource}
+	if !strings.HasPrefix(resource, "tenants/") {
+		candidates = candidates[:0]
+		if ref.Workspace != "" {
+			candidates = append(candidates, "tenants/"+ref.Tenant+"/workspaces/"+ref.Workspace+"/"+resource)
+		}
+		candidates = append(candidates, "tenants/"+ref.Tenant+"/"+resource)
+	}
+
+	providers := []string{ref.Provider}
+	if ref.Provider == "" {
+		providers = []string{from.Provider, ""}
+	}
+	for _, provider := range providers {
+		for _, candidate := range candidates {
+			if state, ok := Lookup(provider, candidate); ok {
+				return candidate, state, true
+			}
+		}
+	}
+	return "", "", false
+}
@@ -0,0 +1,160 @@
+package store
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Backend persists the resources of all stores. Resources are grouped in
+// namespaces, one per kind, and saved as JSON under their resource path.
+type Backend interface {
+	// Load returns all resources saved in a namespace by their key.
+	Load(namespace string) (map[string]json.RawMessage, error)
+	Save(namespace, key string, value json.RawMessage) error
+	Delete(namespace, key string) error
+}
+
+// Memory keeps resources only in the stores themselves, so they are lost when
+// the mockserver stops.
+type Memory struct{}
+
+func (Memory) Load(string) (map[string]json.RawMessage, error) {
+	return nil, nil
+}
+
+func (Memory) Save(string, string, json.RawMessage) error {
+	return nil
+}
+
+func (Memory) Delete(string, string) error {
+	return nil
+}
+
+// File keeps a JSON snapshot of all resources in a file that is rewritten on
+// every change.
+type File struct {
+	path string
+
+	mu         sync.Mutex
+	namespaces map[string]map[string]json.RawMessage
+}
+
+// OpenFile reads the snapshot at path. A missing file is created with the
+// first change.
+func OpenFile(path string) (*File, error) {
+	f := &File{
+		path:       path,
+		namespaces: map[string]map[string]json.RawMessage{},
+	}
+
+	raw, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return f, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(raw, &f.namespaces); err != nil {
+		return nil, fmt.Errorf("parsing state file %s: %w", path, err)
+	}
+	return f, nil
+}
+
+func (f *File) Load(namespace string) (map[string]json.RawMessage, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	items := make(map[string]json.RawMessage, len(f.namespaces[namespace]))
+	for key, value := range f.namespaces[namespace] {
+		items[key] = value
+	}
+	return items, nil
+}
+
+func (f *File) Save(namespace, key string, value json.RawMessage) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.namespaces[namespace] == nil {
+		f.namespaces[namespace] = map[string]json.RawMessage{}
+	}
+	f.namespaces[namespace][key] = value
+	return f.write()
+}
+
+func (f *File) Delete(namespace, key string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if _, ok := f.namespaces[namespace][key]; !ok {
+		return nil
+	}
+	delete(f.namespaces[namespace], key)
+	if len(f.namespaces[namespace]) == 0 {
+		delete(f.namespaces, namespace)
+	}
+	return f.write()
+}
+
+// write replaces the snapshot atomically, so that a crash never leaves a
+// half-written file behind.
+func (f *File) write() error {
+	raw, err := json.MarshalIndent(f.namespaces, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(f.path), filepath.Base(f.path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(raw); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), f.path)
+}
+
+var (
+	backendMu sync.RWMutex
+	backend   Backend = Memory{}
+)
+
+// Open returns the backend of the given type: "memory" or "file", which
+// keeps its snapshot at path.
+func Open(kind, path string) (Backend, error) {
+	switch kind {
+	case "", "memory":
+		return Memory{}, nil
+	case "file":
+		if path == "" {
+			return nil, errors.New("file storage requires a path")
+		}
+		return OpenFile(path)
+	}
+	return nil, fmt.Errorf("unknown storage %q, use memory or file", kind)
+}
+
+// Configure sets the backend of all stores created afterwards.
+func Configure(b Backend) {
+	backendMu.Lock()
+	defer backendMu.Unlock()
+
+	backend = b
+}
+
+func configuredBackend() Backend {
+	backendMu.RLock()
+	defer backendMu.RUnlock()
+
+	return backend
+}
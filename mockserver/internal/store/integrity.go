@@ -0,0 +1,113 @@
+package store
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"cape-project.eu/mockserver/internal/registry"
+)
+
+// checkIntegrity makes sure a resource is written into an existing workspace
+// and parent, and that its references point to existing resources.
+func (s *Store[T]) checkIntegrity(ref Ref, item *T) error {
+	scope := ""
+	if ref.Workspace != "" {
+		scope = "tenants/" + ref.Tenant + "/workspaces/" + ref.Workspace
+		if err := s.checkExists("", scope, "workspace "+ref.Workspace); err != nil {
+			return err
+		}
+	}
+	if ref.Parent != "" {
+		parent := strings.TrimPrefix(scope+"/"+ref.Parent, "/")
+		if scope == "" {
+			parent = "tenants/" + ref.Tenant + "/" + ref.Parent
+		}
+		if err := s.checkExists(s.kind.Provider, parent, ref.Parent); err != nil {
+			return err
+		}
+	}
+	if len(s.kind.References) == 0 {
+		return nil
+	}
+
+	raw, err := json.Marshal(item)
+	if err != nil {
+		return err
+	}
+	var document any
+	if err := json.Unmarshal(raw, &document); err != nil {
+		return err
+	}
+
+	from := registry.Reference{Provider: s.kind.Provider, Tenant: ref.Tenant, Workspace: ref.Workspace}
+	for _, path := range s.kind.References {
+		for _, found := range referenceValues(document, strings.Split(path, "."), "") {
+			pointer := found.pointer
+			target, ok := registry.ParseReference(found.value)
+			if !ok {
+				return &Error{Status: http.StatusUnprocessableEntity, Message: "invalid reference", Pointer: pointer}
+			}
+			_, state, ok := registry.Resolve(from, target)
+			if !ok {
+				return &Error{Status: http.StatusUnprocessableEntity, Message: "referenced resource " + target.Resource + " not found", Pointer: pointer}
+			}
+			if state == s.kind.States.Deleting {
+				return &Error{Status: http.StatusUnprocessableEntity, Message: "referenced resource " + target.Resource + " is being deleted", Pointer: pointer}
+			}
+		}
+	}
+	return nil
+}
+
+// checkExists returns a 404 error if the resource with the given path does
+// not exist and a 409 error if it is being deleted.
+func (s *Store[T]) checkExists(provider, resource, description string) error {
+	state, ok := registry.Lookup(provider, resource)
+	if !ok {
+		return Errorf(http.StatusNotFound, "%s not found", description)
+	}
+	if state == s.kind.States.Deleting {
+		return Errorf(http.StatusConflict, "%s is being deleted", description)
+	}
+	return nil
+}
+
+// referenceValue is a reference found in a resource and its JSON pointer.
+type referenceValue struct {
+	pointer string
+	value   any
+}
+
+// referenceValues returns the values at a path like "spec.nics[].subnetRef"
+// in a JSON document.
+func referenceValues(document any, path []string, pointer string) []referenceValue {
+	var values []referenceValue
+	if len(path) == 0 {
+		if document != nil {
+			values = append(values, referenceValue{pointer: pointer, value: document})
+		}
+		return values
+	}
+
+	object, ok := document.(map[string]any)
+	if !ok {
+		return values
+	}
+	name, isList := strings.CutSuffix(path[0], "[]")
+	child, ok := object[name]
+	if !ok {
+		return values
+	}
+	pointer += "/" + name
+	if !isList {
+		return referenceValues(child, path[1:], pointer)
+	}
+
+	items, _ := child.([]any)
+	for i, item := range items {
+		values = append(values, referenceValues(item, path[1:], pointer+"/"+strconv.Itoa(i))...)
+	}
+	return values
+}
@@ -0,0 +1,189 @@
+package store
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type testResource struct {
+	Metadata *Metadata      `json:"metadata,omitempty"`
+	Spec     map[string]any `json:"spec,omitempty"`
+	State    string         `json:"state,omitempty"`
+}
+
+var testStates = States{
+	Pending:  "pending",
+	Creating: "creating",
+	Active:   "active",
+	Updating: "updating",
+	Deleting: "deleting",
+	Error:    "error",
+}
+
+// newTestStore creates a store whose resources stay in their first state
+// for the duration of a test.
+func newTestStore(provider, name, collection string, references ...string) *Store[testResource] {
+	slow := time.Hour
+	return New(Kind[testResource]{
+		Name:       name,
+		Title:      name,
+		Collection: collection,
+		Provider:   provider,
+		APIVersion: "v1",
+		States:     testStates,
+		Lifecycle:  Lifecycle{Creating: slow, Active: slow, Update: slow, Deletion: slow},
+		Metadata: func(r *testResource) (Metadata, bool) {
+			if r.Metadata == nil {
+				return Metadata{}, false
+			}
+			return *r.Metadata, true
+		},
+		SetMetadata: func(r *testResource, metadata Metadata) { r.Metadata = &metadata },
+		State:       func(r *testResource) string { return r.State },
+		SetState:    func(r *testResource, state string, _ Condition) { r.State = state },
+		Carry: func(dst, src *testResource) {
+			dst.Metadata = src.Metadata
+			dst.State = src.State
+		},
+		References: references,
+	})
+}
+
+func put(t *testing.T, s *Store[testResource], ref Ref, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPut, "/", strings.NewReader(body))
+	c.Request.Header.Set("Content-Type", "application/json")
+	s.Put(c, ref, nil)
+	return w
+}
+
+func del(t *testing.T, s *Store[testResource], ref Ref) *httptest.ResponseRecorder {
+	t.Helper()
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodDelete, "/", nil)
+	s.Delete(c, ref, nil)
+	return w
+}
+
+// problem decodes the problem details the store answers errors with.
+func problem(t *testing.T, w *httptest.ResponseRecorder) (string, string) {
+	t.Helper()
+	var body struct {
+		Detail  string `json:"detail"`
+		Sources []struct {
+			Pointer string `json:"pointer"`
+		} `json:"sources"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decoding %s: %v", w.Body, err)
+	}
+	pointer := ""
+	if len(body.Sources) > 0 {
+		pointer = body.Sources[0].Pointer
+	}
+	return body.Detail, pointer
+}
+
+func TestIntegrity(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	workspaces := newTestStore("seca.workspace", "workspace", "workspaces")
+	networks := newTestStore("seca.network", "network", "networks")
+	subnets := newTestStore("seca.network", "subnet", "subnets")
+	volumes := newTestStore("seca.storage", "block-storage", "block-storages", "spec.networkRef", "spec.attachments[].subnetRef")
+
+	const tenant = "integrity"
+	workspace := Ref{Tenant: tenant, Name: "ws"}
+	network := Ref{Tenant: tenant, Workspace: "ws", Name: "net"}
+	if w := put(t, workspaces, workspace, `{}`); w.Code != http.StatusCreated {
+		t.Fatalf("creating the workspace = %d: %s", w.Code, w.Body)
+	}
+	if w := put(t, networks, network, `{}`); w.Code != http.StatusCreated {
+		t.Fatalf("creating the network = %d: %s", w.Code, w.Body)
+	}
+
+	tests := []struct {
+		name        string
+		store       *Store[testResource]
+		ref         Ref
+		body        string
+		wantStatus  int
+		wantDetail  string
+		wantPointer string
+	}{
+		{name: "missing workspace", store: volumes, ref: Ref{Tenant: tenant, Workspace: "other", Name: "v"}, body: `{}`, wantStatus: http.StatusNotFound, wantDetail: "workspace other not found"},
+		{name: "missing parent", store: subnets, ref: Ref{Tenant: tenant, Workspace: "ws", Parent: "networks/other", Name: "s"}, body: `{}`, wantStatus: http.StatusNotFound, wantDetail: "networks/other not found"},
+		{name: "existing parent", store: subnets, ref: Ref{Tenant: tenant, Workspace: "ws", Parent: "networks/net", Name: "s"}, body: `{}`, wantStatus: http.StatusCreated},
+		{name: "missing reference", store: volumes, ref: Ref{Tenant: tenant, Workspace: "ws", Name: "v"}, body: `{"spec": {"networkRef": "networks/other"}}`, wantStatus: http.StatusUnprocessableEntity, wantDetail: "referenced resource networks/other not found", wantPointer: "/spec/networkRef"},
+		{name: "invalid reference", store: volumes, ref: Ref{Tenant: tenant, Workspace: "ws", Name: "v"}, body: `{"spec": {"networkRef": 5}}`, wantStatus: http.StatusUnprocessableEntity, wantDetail: "invalid reference", wantPointer: "/spec/networkRef"},
+		{name: "missing reference in a list", store: volumes, ref: Ref{Tenant: tenant, Workspace: "ws", Name: "v"}, body: `{"spec": {"attachments": [{"subnetRef": "networks/net/subnets/s"}, {"subnetRef": "networks/net/subnets/other"}]}}`, wantStatus: http.StatusUnprocessableEntity, wantPointer: "/spec/attachments/1/subnetRef"},
+		{name: "reference by URN", store: volumes, ref: Ref{Tenant: tenant, Workspace: "ws", Name: "v"}, body: `{"spec": {"networkRef": "seca.network/v1/tenants/integrity/workspaces/ws/networks/net"}}`, wantStatus: http.StatusCreated},
+		{name: "existing references", store: volumes, ref: Ref{Tenant: tenant, Workspace: "ws", Name: "v"}, body: `{"spec": {"networkRef": "networks/net", "attachments": [{"subnetRef": "networks/net/subnets/s"}]}}`, wantStatus: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := put(t, tt.store, tt.ref, tt.body)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+			if w.Code >= 400 {
+				detail, pointer := problem(t, w)
+				if !strings.Contains(detail, tt.wantDetail) || pointer != tt.wantPointer {
+					t.Errorf("problem = %q at %q, want %q at %q", detail, pointer, tt.wantDetail, tt.wantPointer)
+				}
+			}
+		})
+	}
+
+	t.Run("workspace with resources", func(t *testing.T) {
+		w := del(t, workspaces, workspace)
+		if w.Code != http.StatusConflict {
+			t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusConflict, w.Body)
+		}
+		if detail, _ := problem(t, w); !strings.Contains(detail, "workspace ws still contains 3 resources") {
+			t.Errorf("detail = %q", detail)
+		}
+		if _, ok := workspaces.Lookup(workspace); !ok {
+			t.Error("the workspace was deleted")
+		}
+	})
+
+	t.Run("referenced resource being deleted", func(t *testing.T) {
+		other := Ref{Tenant: tenant, Workspace: "ws", Name: "old"}
+		if w := put(t, networks, other, `{}`); w.Code != http.StatusCreated {
+			t.Fatalf("creating the network = %d: %s", w.Code, w.Body)
+		}
+		if w := del(t, networks, other); w.Code != http.StatusAccepted {
+			t.Fatalf("deleting the network = %d: %s", w.Code, w.Body)
+		}
+		w := put(t, volumes, Ref{Tenant: tenant, Workspace: "ws", Name: "v2"}, `{"spec": {"networkRef": "networks/old"}}`)
+		if w.Code != http.StatusUnprocessableEntity {
+			t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusUnprocessableEntity, w.Body)
+		}
+		if detail, _ := problem(t, w); !strings.Contains(detail, "is being deleted") {
+			t.Errorf("detail = %q", detail)
+		}
+	})
+
+	t.Run("workspace being deleted", func(t *testing.T) {
+		empty := Ref{Tenant: tenant, Name: "empty"}
+		if w := put(t, workspaces, empty, `{}`); w.Code != http.StatusCreated {
+			t.Fatalf("creating the workspace = %d: %s", w.Code, w.Body)
+		}
+		if w := del(t, workspaces, empty); w.Code != http.StatusAccepted {
+			t.Fatalf("deleting the empty workspace = %d: %s", w.Code, w.Body)
+		}
+		w := put(t, networks, Ref{Tenant: tenant, Workspace: "empty", Name: "net"}, `{}`)
+		if w.Code != http.StatusConflict {
+			t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusConflict, w.Body)
+		}
+	})
+}
@@ -0,0 +1,78 @@
+package store
+
+import (
+	"encoding/base64"
+	"net/http"
+	"slices"
+	"strings"
+
+	"cape-project.eu/mockserver/internal/labels"
+)
+
+// ListOptions are the query parameters of list operations.
+type ListOptions struct {
+	// Labels is a selector like "tier=RD*,iops>=500", see labels.MatchSelector.
+	Labels string
+	// Limit is the maximum number of items of a page, unlimited if zero.
+	Limit int
+	// SkipToken continues a list where the previous page ended.
+	SkipToken string
+}
+
+// NewListOptions collects the optional list query parameters of a request.
+func NewListOptions[L, S ~string, N ~int | ~int32 | ~int64](labels *L, limit *N, skipToken *S) ListOptions {
+	var options ListOptions
+	if labels != nil {
+		options.Labels = string(*labels)
+	}
+	if limit != nil {
+		options.Limit = int(*limit)
+	}
+	if skipToken != nil {
+		options.SkipToken = string(*skipToken)
+	}
+	return options
+}
+
+// Paginate returns the page of the items selected by options, ordered by
+// name, and the token to continue with if there are more. Items without
+// labels function match label selectors like items without labels.
+func Paginate[T any](items []T, name func(item *T) string, itemLabels func(item *T) map[string]string, options ListOptions) ([]T, *string, error) {
+	if options.Limit < 0 {
+		return nil, nil, &Error{Status: http.StatusBadRequest, Message: "limit must not be negative"}
+	}
+	after := ""
+	if options.SkipToken != "" {
+		decoded, err := base64.RawURLEncoding.DecodeString(options.SkipToken)
+		if err != nil || len(decoded) == 0 {
+			return nil, nil, &Error{Status: http.StatusBadRequest, Message: "invalid skipToken"}
+		}
+		after = string(decoded)
+	}
+
+	sorted := slices.Clone(items)
+	slices.SortStableFunc(sorted, func(a, b T) int {
+		return strings.Compare(name(&a), name(&b))
+	})
+
+	page := make([]T, 0)
+	for i := range sorted {
+		item := &sorted[i]
+		if after != "" && name(item) <= after {
+			continue
+		}
+		var selected map[string]string
+		if itemLabels != nil {
+			selected = itemLabels(item)
+		}
+		if !labels.MatchSelector(selected, options.Labels) {
+			continue
+		}
+		if options.Limit > 0 && len(page) == options.Limit {
+			token := base64.RawURLEncoding.EncodeToString([]byte(name(&page[len(page)-1])))
+			return page, &token, nil
+		}
+		page = append(page, *item)
+	}
+	return page, nil, nil
+}
@@ -0,0 +1,124 @@
+package store
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"testing"
+)
+
+type testItem struct {
+	name   string
+	labels map[string]string
+}
+
+func itemName(item *testItem) string              { return item.name }
+func itemLabels(item *testItem) map[string]string { return item.labels }
+
+func names(items []testItem) []string {
+	result := make([]string, len(items))
+	for i, item := range items {
+		result[i] = item.name
+	}
+	return result
+}
+
+var testItems = []testItem{
+	{name: "d", labels: map[string]string{"tier": "RD500"}},
+	{name: "b", labels: map[string]string{"tier": "RD100"}},
+	{name: "a", labels: map[string]string{"tier": "RD500"}},
+	{name: "c"},
+}
+
+func TestPaginate(t *testing.T) {
+	tests := []struct {
+		name    string
+		options ListOptions
+		want    []string
+		more    bool
+	}{
+		{name: "ordered by name", want: []string{"a", "b", "c", "d"}},
+		{name: "label filter", options: ListOptions{Labels: "tier=RD500"}, want: []string{"a", "d"}},
+		{name: "label pattern", options: ListOptions{Labels: "tier=RD*"}, want: []string{"a", "b", "d"}},
+		{name: "no match", options: ListOptions{Labels: "tier=RD900"}, want: []string{}},
+		{name: "limit", options: ListOptions{Limit: 3}, want: []string{"a", "b", "c"}, more: true},
+		{name: "limit of all items", options: ListOptions{Limit: 4}, want: []string{"a", "b", "c", "d"}},
+		{name: "limit of all selected items", options: ListOptions{Labels: "tier=RD500", Limit: 2}, want: []string{"a", "d"}},
+		{name: "limit beyond all items", options: ListOptions{Limit: 10}, want: []string{"a", "b", "c", "d"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page, token, err := Paginate(testItems, itemName, itemLabels, tt.options)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := names(page); !slices.Equal(got, tt.want) {
+				t.Errorf("page = %v, want %v", got, tt.want)
+			}
+			if (token != nil) != tt.more {
+				t.Errorf("skip token = %v, want one: %v", token, tt.more)
+			}
+		})
+	}
+	if names(testItems)[0] != "d" {
+		t.Error("Paginate() reordered its input")
+	}
+}
+
+func TestPaginateSkipToken(t *testing.T) {
+	for _, options := range []ListOptions{{Limit: 1}, {Limit: 2}, {Limit: 3, Labels: "tier=RD*"}} {
+		var got []string
+		for pages := 0; ; pages++ {
+			if pages > len(testItems) {
+				t.Fatalf("%+v: skip tokens do not end", options)
+			}
+			page, token, err := Paginate(testItems, itemName, itemLabels, options)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got = append(got, names(page)...)
+			if token == nil {
+				break
+			}
+			options.SkipToken = *token
+		}
+
+		options.SkipToken = ""
+		want, _, _ := Paginate(testItems, itemName, itemLabels, ListOptions{Labels: options.Labels})
+		if !slices.Equal(got, names(want)) {
+			t.Errorf("%+v: pages = %v, want %v", options, got, names(want))
+		}
+	}
+}
+
+func TestPaginateInvalid(t *testing.T) {
+	for _, options := range []ListOptions{{Limit: -1}, {SkipToken: "not base64!"}, {SkipToken: "="}} {
+		_, _, err := Paginate(testItems, itemName, itemLabels, options)
+		var storeErr *Error
+		if !errors.As(err, &storeErr) || storeErr.Status != http.StatusBadRequest {
+			t.Errorf("Paginate(%+v) = %v, want a 400", options, err)
+		}
+	}
+}
+
+func TestPaginateWithoutLabels(t *testing.T) {
+	page, _, err := Paginate(testItems, itemName, nil, ListOptions{Labels: "tier=RD500"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page) != 0 {
+		t.Errorf("page = %v, want items without labels not to match", names(page))
+	}
+}
+
+func TestNewListOptions(t *testing.T) {
+	type selector string
+	labels, skipToken, limit := selector("tier=RD500"), "abc", int32(5)
+	options := NewListOptions(&labels, &limit, &skipToken)
+	if options != (ListOptions{Labels: "tier=RD500", Limit: 5, SkipToken: "abc"}) {
+		t.Errorf("NewListOptions() = %+v", options)
+	}
+	if options := NewListOptions[string, string, int](nil, nil, nil); options != (ListOptions{}) {
+		t.Errorf("NewListOptions(nil) = %+v", options)
+	}
+}
@@ -0,0 +1,149 @@
+package store
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"cape-project.eu/mockserver/internal/region"
+)
+
+// The methods in this file share a store with the other servers and the
+// admin API, see registry.Collection.
+
+func (s *Store[T]) Name() string {
+	return s.namespace
+}
+
+func (s *Store[T]) Provider() string {
+	return s.kind.Provider
+}
+
+func (s *Store[T]) State(resource string) (string, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	item, ok := s.items[resource]
+	if !ok {
+		return "", false
+	}
+	return s.kind.State(&item), true
+}
+
+func (s *Store[T]) Count(prefix string) int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	count := 0
+	for key := range s.items {
+		if strings.HasPrefix(key, prefix) {
+			count++
+		}
+	}
+	return count
+}
+
+func (s *Store[T]) Reset(tenant string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	prefix := "tenants/" + tenant + "/"
+	for key := range s.items {
+		if tenant == "" || strings.HasPrefix(key, prefix) {
+			s.remove(key)
+		}
+	}
+}
+
+func (s *Store[T]) Dump() any {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	items := make(map[string]T, len(s.items))
+	for key, item := range s.items {
+		items[key] = item
+	}
+	return items
+}
+
+// Seed adds a fixture as active resource. Its metadata needs at least tenant
+// and name; nested resources also need their resource path.
+func (s *Store[T]) Seed(provider, kind string, raw json.RawMessage) (bool, error) {
+	if kind != s.kind.Name || provider != "" && provider != s.kind.Provider && provider != s.kind.Provider+"/"+s.kind.APIVersion {
+		return false, nil
+	}
+
+	var item T
+	if err := json.Unmarshal(raw, &item); err != nil {
+		return true, err
+	}
+	metadata, ok := s.kind.Metadata(&item)
+	if !ok || metadata.Tenant == "" || metadata.Name == "" {
+		return true, fmt.Errorf("metadata needs tenant and name")
+	}
+
+	key := metadata.Resource
+	if key == "" {
+		key = s.kind.Path(Ref{Tenant: metadata.Tenant, Workspace: metadata.Workspace, Name: metadata.Name})
+	}
+	if !strings.HasSuffix(key, "/"+s.kind.Collection+"/"+metadata.Name) {
+		return true, fmt.Errorf("resource %s is not a path of %s %s", key, s.kind.Collection, metadata.Name)
+	}
+
+	now := time.Now().UTC()
+	s.kind.SetMetadata(&item, Metadata{
+		APIVersion:      s.kind.APIVersion,
+		Kind:            s.kind.Name,
+		Provider:        s.kind.Provider,
+		Region:          region.Default(),
+		Tenant:          metadata.Tenant,
+		Workspace:       metadata.Workspace,
+		Name:            metadata.Name,
+		Resource:        key,
+		ResourceVersion: 1,
+		Verb:            "put",
+		CreatedAt:       now,
+		LastModifiedAt:  now,
+	})
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if existing, exists := s.items[key]; exists {
+		if previous, ok := s.kind.Metadata(&existing); ok {
+			metadata, _ := s.kind.Metadata(&item)
+			metadata.ResourceVersion = previous.ResourceVersion + 1
+			s.kind.SetMetadata(&item, metadata)
+		}
+	}
+	s.SetState(&item, s.kind.States.Active)
+	s.save(key, item)
+	return true, nil
+}
+
+// ForceState moves a resource into a state. Transitions that are still
+// scheduled for it are dropped, so it stays there until it is changed.
+func (s *Store[T]) ForceState(resource, state string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	item, ok := s.items[resource]
+	if !ok {
+		return false, nil
+	}
+	states := s.kind.States
+	switch state {
+	case states.Pending, states.Creating, states.Active, states.Updating, states.Deleting, states.Error:
+	default:
+		return true, fmt.Errorf("unknown state %q", state)
+	}
+
+	metadata, _ := s.kind.Metadata(&item)
+	metadata.ResourceVersion++
+	metadata.LastModifiedAt = time.Now().UTC()
+	s.kind.SetMetadata(&item, metadata)
+	s.SetState(&item, state)
+	s.save(resource, item)
+	return true, nil
+}
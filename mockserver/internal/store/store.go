@@ -0,0 +1,581 @@
+package store
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"cape-project.eu/mockserver/internal/fault"
+	"cape-project.eu/mockserver/internal/precondition"
+	"cape-project.eu/mockserver/internal/region"
+	"cape-project.eu/mockserver/internal/registry"
+	"github.com/gin-gonic/gin"
+)
+
+// Metadata is the part of a resource's metadata that the store maintains.
+type Metadata struct {
+	APIVersion      string
+	Kind            string
+	Provider        string
+	Region          string
+	Tenant          string
+	Workspace       string
+	Name            string
+	Resource        string
+	ResourceVersion int64
+	Verb            string
+	CreatedAt       time.Time
+	LastModifiedAt  time.Time
+}
+
+// Condition is added to the status of a resource on every state change.
+type Condition struct {
+	At      time.Time
+	Message string
+	Reason  string
+}
+
+// States holds the values of the ResourceState enum of an API.
+type States struct {
+	Pending  string
+	Creating string
+	Active   string
+	Updating string
+	Deleting string
+	// Error is entered instead of active if a fault is injected.
+	Error string
+}
+
+// Lifecycle holds the delays after which a resource moves on to its next
+// state.
+type Lifecycle struct {
+	// Creating and Active are counted from the creation request.
+	Creating time.Duration
+	Active   time.Duration
+	// Update is the time an update takes until the resource is active again.
+	Update time.Duration
+	// Deletion is the time a deleting resource stays visible.
+	Deletion time.Duration
+}
+
+// DefaultLifecycle is used by kinds that do not declare their own.
+var DefaultLifecycle = Lifecycle{
+	Creating: 100 * time.Millisecond,
+	Active:   600 * time.Millisecond,
+	Update:   500 * time.Millisecond,
+	Deletion: 500 * time.Millisecond,
+}
+
+// Kind describes a resource type of an API and how its model is accessed.
+type Kind[T any] struct {
+	// Name is the kind in kebab case, e.g. "block-storage".
+	Name string
+	// Title is used in condition messages, e.g. "BlockStorage".
+	Title string
+	// Collection is the path segment of the resources, e.g. "block-storages".
+	Collection string
+	Provider   string
+	APIVersion string
+	States     States
+	Lifecycle  Lifecycle
+
+	Metadata    func(item *T) (Metadata, bool)
+	SetMetadata func(item *T, metadata Metadata)
+	State       func(item *T) string
+	SetState    func(item *T, state string, condition Condition)
+	// Labels returns the labels of a resource, nil if the kind has none.
+	Labels func(item *T) map[string]string
+	// Carry copies metadata and status of a stored resource into the
+	// resource replacing it on update.
+	Carry func(dst, src *T)
+	// References are the paths of the Reference fields, like "spec.skuRef"
+	// or "spec.nics[].subnetRef". They must point to existing resources.
+	References []string
+}
+
+// Ref addresses a resource, or the collection it is in if Name is empty.
+type Ref struct {
+	Tenant    string
+	Workspace string
+	// Parent is the path of the resource this one is nested in, relative to
+	// the workspace, e.g. "networks/my-network".
+	Parent string
+	Name   string
+}
+
+// Path returns the resource path of ref, e.g.
+// "tenants/t/workspaces/w/block-storages/b".
+func (k Kind[T]) Path(ref Ref) string {
+	var b strings.Builder
+	b.WriteString("tenants/")
+	b.WriteString(ref.Tenant)
+	if ref.Workspace != "" {
+		b.WriteString("/workspaces/")
+		b.WriteString(ref.Workspace)
+	}
+	if ref.Parent != "" {
+		b.WriteString("/")
+		b.WriteString(ref.Parent)
+	}
+	b.WriteString("/")
+	b.WriteString(k.Collection)
+	if ref.Name != "" {
+		b.WriteString("/")
+		b.WriteString(ref.Name)
+	}
+	return b.String()
+}
+
+// Error is answered with its status code by the handlers of the store.
+type Error struct {
+	Status  int
+	Message string
+	// Pointer is the JSON pointer to the invalid part of the request, if any.
+	Pointer string
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Errorf returns an Error with a formatted message.
+func Errorf(status int, format string, args ...any) error {
+	return &Error{Status: status, Message: fmt.Sprintf(format, args...)}
+}
+
+// Fail answers the request with err, using its status if it is an Error.
+// Errors are problem details as the SecAPI answers them, with the "error"
+// field the mockserver uses elsewhere.
+func Fail(c *gin.Context, err error) {
+	var storeErr *Error
+	if errors.As(err, &storeErr) {
+		problem := gin.H{
+			"status": storeErr.Status,
+			"title":  http.StatusText(storeErr.Status),
+			"detail": storeErr.Message,
+			"error":  storeErr.Message,
+		}
+		if storeErr.Pointer != "" {
+			problem["sources"] = []gin.H{{"pointer": storeErr.Pointer, "detail": storeErr.Message}}
+		}
+		c.Header("Content-Type", "application/problem+json")
+		c.JSON(storeErr.Status, problem)
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+}
+
+// Store keeps the resources of one kind in memory and moves them through
+// their lifecycle: pending, creating and active after creation, updating
+// and active after an update, deleting before they are removed.
+type Store[T any] struct {
+	kind Kind[T]
+
+	backend   Backend
+	namespace string
+
+	mu    sync.RWMutex
+	items map[string]T
+
+	beforeWrite  func(ref Ref, item *T) error
+	beforeDelete func(ref Ref, item T) error
+	onState      func(item *T, from, to string)
+}
+
+// New creates a store for a kind, restoring its resources from the
+// configured backend.
+func New[T any](kind Kind[T]) *Store[T] {
+	if kind.Lifecycle == (Lifecycle{}) {
+		kind.Lifecycle = DefaultLifecycle
+	}
+	s := &Store[T]{
+		kind:      kind,
+		backend:   configuredBackend(),
+		namespace: kind.Provider + "/" + kind.APIVersion + "/" + kind.Collection,
+		items:     map[string]T{},
+	}
+	s.restore()
+	registry.Register(s)
+	return s
+}
+
+// restore loads the saved resources and resumes the state transitions that
+// were in progress when they were saved.
+func (s *Store[T]) restore() {
+	saved, err := s.backend.Load(s.namespace)
+	if err != nil {
+		log.Printf("loading %s failed: %v", s.namespace, err)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for key, raw := range saved {
+		var item T
+		if err := json.Unmarshal(raw, &item); err != nil {
+			log.Printf("loading %s %s failed: %v", s.namespace, key, err)
+			continue
+		}
+		s.items[key] = item
+
+		metadata, _ := s.kind.Metadata(&item)
+		switch s.kind.State(&item) {
+		case s.kind.States.Pending, s.kind.States.Creating, s.kind.States.Updating:
+			s.scheduleState(key, metadata.ResourceVersion, s.kind.Lifecycle.Update, s.kind.States.Active)
+		case s.kind.States.Deleting:
+			s.schedule(key, metadata.ResourceVersion, s.kind.Lifecycle.Deletion, func(*T) bool {
+				return false
+			})
+		}
+	}
+}
+
+// Kind returns the kind the store was created for.
+func (s *Store[T]) Kind() Kind[T] {
+	return s.kind
+}
+
+// SetLifecycle changes the delays of the state transitions.
+func (s *Store[T]) SetLifecycle(lifecycle Lifecycle) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.kind.Lifecycle = lifecycle
+}
+
+// BeforeWrite registers a check run before a resource is created or
+// updated. An error rejects the request.
+func (s *Store[T]) BeforeWrite(fn func(ref Ref, item *T) error) {
+	s.beforeWrite = fn
+}
+
+// BeforeDelete registers a check run before a resource is deleted. An error
+// rejects the request.
+func (s *Store[T]) BeforeDelete(fn func(ref Ref, item T) error) {
+	s.beforeDelete = fn
+}
+
+// OnStateChange registers a function called whenever a resource moves from
+// one state to another, e.g. to fill in status fields. It runs while the store
+// is locked.
+func (s *Store[T]) OnStateChange(fn func(item *T, from, to string)) {
+	s.onState = fn
+}
+
+// List returns all resources in the collection addressed by ref, ordered by
+// name.
+func (s *Store[T]) List(ref Ref) []T {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	ref.Name = ""
+	prefix := s.kind.Path(ref) + "/"
+	keys := make([]string, 0)
+	for key := range s.items {
+		if strings.HasPrefix(key, prefix) && !strings.Contains(key[len(prefix):], "/") {
+			keys = append(keys, key)
+		}
+	}
+	slices.Sort(keys)
+
+	items := make([]T, len(keys))
+	for i, key := range keys {
+		items[i] = s.items[key]
+	}
+	return items
+}
+
+// Page returns the page of the collection addressed by ref that options
+// select, see Paginate.
+func (s *Store[T]) Page(ref Ref, options ListOptions) ([]T, *string, error) {
+	name := func(item *T) string {
+		metadata, _ := s.kind.Metadata(item)
+		return metadata.Name
+	}
+	return Paginate(s.List(ref), name, s.kind.Labels, options)
+}
+
+// Lookup returns the resource addressed by ref.
+func (s *Store[T]) Lookup(ref Ref) (T, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	item, ok := s.items[s.kind.Path(ref)]
+	return item, ok
+}
+
+// Modify changes the resource addressed by ref in place. An error returned by
+// fn leaves the resource untouched and is passed on.
+func (s *Store[T]) Modify(ref Ref, fn func(item *T) error) (T, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := s.kind.Path(ref)
+	item, ok := s.items[key]
+	if !ok {
+		var zero T
+		return zero, Errorf(http.StatusNotFound, "%s not found", s.kind.Name)
+	}
+	if err := fn(&item); err != nil {
+		var zero T
+		return zero, err
+	}
+	s.save(key, item)
+	return item, nil
+}
+
+// Schedule runs fn on the resource addressed by ref after delay, unless the
+// resource was removed or changed its version in the meantime.
+func (s *Store[T]) Schedule(ref Ref, version int64, delay time.Duration, fn func(item *T)) {
+	s.schedule(s.kind.Path(ref), version, delay, func(item *T) bool {
+		fn(item)
+		return true
+	})
+}
+
+// SetState moves a resource to a new state, recording a condition. It must
+// be called on resources passed to Modify or Schedule.
+func (s *Store[T]) SetState(item *T, state string) {
+	previous := s.kind.State(item)
+	if previous == state {
+		return
+	}
+	s.AddCondition(item, state, fmt.Sprintf("%s is now in %s state", s.kind.Title, state), "stateChange")
+	if s.onState != nil {
+		s.onState(item, previous, state)
+	}
+}
+
+// AddCondition records a condition with the given state on a resource.
+func (s *Store[T]) AddCondition(item *T, state, message, reason string) {
+	s.kind.SetState(item, state, Condition{
+		At:      time.Now().UTC(),
+		Message: message,
+		Reason:  reason,
+	})
+}
+
+// Get answers with the resource addressed by ref.
+func (s *Store[T]) Get(c *gin.Context, ref Ref) {
+	item, ok := s.Lookup(ref)
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": s.kind.Name + " not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, item)
+}
+
+// Put creates or replaces the resource addressed by ref with the request
+// body. If expected is given, the resource must exist in that version.
+func (s *Store[T]) Put(c *gin.Context, ref Ref, expected *int64) {
+	var item T
+	if err := c.ShouldBindJSON(&item); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if s.beforeWrite != nil {
+		if err := s.beforeWrite(ref, &item); err != nil {
+			Fail(c, err)
+			return
+		}
+	}
+	if err := s.checkIntegrity(ref, &item); err != nil {
+		Fail(c, err)
+		return
+	}
+
+	now := time.Now().UTC()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := s.kind.Path(ref)
+	existing, exists := s.items[key]
+	var version int64
+	if exists {
+		if metadata, ok := s.kind.Metadata(&existing); ok {
+			version = metadata.ResourceVersion
+		}
+	}
+	if !precondition.Holds(c, expected, exists, version) {
+		return
+	}
+
+	metadata := Metadata{
+		APIVersion:      s.kind.APIVersion,
+		Kind:            s.kind.Name,
+		Provider:        s.kind.Provider,
+		Region:          region.Default(),
+		Tenant:          ref.Tenant,
+		Workspace:       ref.Workspace,
+		Name:            ref.Name,
+		Resource:        key,
+		ResourceVersion: version + 1,
+		Verb:            "put",
+		CreatedAt:       now,
+		LastModifiedAt:  now,
+	}
+	if !exists {
+		s.kind.SetMetadata(&item, metadata)
+		s.SetState(&item, s.kind.States.Pending)
+
+		s.save(key, item)
+		faults := s.lifecycleFaults(ref)
+		s.scheduleState(key, metadata.ResourceVersion, faults.Delay+s.kind.Lifecycle.Creating, s.kind.States.Creating)
+		s.scheduleActive(key, metadata.ResourceVersion, faults.Delay+s.kind.Lifecycle.Active, faults.Fail)
+		c.JSON(http.StatusCreated, item)
+		return
+	}
+
+	if previous, ok := s.kind.Metadata(&existing); ok && !previous.CreatedAt.IsZero() {
+		metadata.CreatedAt = previous.CreatedAt
+	}
+	s.kind.Carry(&item, &existing)
+	s.kind.SetMetadata(&item, metadata)
+	s.SetState(&item, s.kind.States.Updating)
+
+	s.save(key, item)
+	faults := s.lifecycleFaults(ref)
+	s.scheduleActive(key, metadata.ResourceVersion, faults.Delay+s.kind.Lifecycle.Update, faults.Fail)
+	c.JSON(http.StatusOK, item)
+}
+
+// Delete marks the resource addressed by ref as deleting and removes it
+// after a while. If expected is given, the resource must be in that version.
+func (s *Store[T]) Delete(c *gin.Context, ref Ref, expected *int64) {
+	if s.beforeDelete != nil {
+		if item, ok := s.Lookup(ref); ok {
+			if err := s.beforeDelete(ref, item); err != nil {
+				Fail(c, err)
+				return
+			}
+		}
+	}
+	if children := registry.Children(s.kind.Path(ref)); children > 0 {
+		Fail(c, Errorf(http.StatusConflict, "%s %s still contains %d resources, delete them first", s.kind.Name, ref.Name, children))
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := s.kind.Path(ref)
+	item, ok := s.items[key]
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": s.kind.Name + " not found"})
+		return
+	}
+	metadata, _ := s.kind.Metadata(&item)
+	if !precondition.Holds(c, expected, true, metadata.ResourceVersion) {
+		return
+	}
+
+	if s.kind.State(&item) != s.kind.States.Deleting {
+		metadata.ResourceVersion++
+		metadata.Verb = "delete"
+		s.kind.SetMetadata(&item, metadata)
+		s.SetState(&item, s.kind.States.Deleting)
+		s.save(key, item)
+		s.schedule(key, metadata.ResourceVersion, s.kind.Lifecycle.Deletion, func(*T) bool {
+			return false
+		})
+	}
+
+	response := gin.H{
+		"deleted": true,
+		"tenant":  ref.Tenant,
+		"name":    ref.Name,
+	}
+	if ref.Workspace != "" {
+		response["workspace"] = ref.Workspace
+	}
+	c.JSON(http.StatusAccepted, response)
+}
+
+// lifecycleFaults returns the faults injected into the lifecycle of a
+// resource, see fault.Lifecycle.
+func (s *Store[T]) lifecycleFaults(ref Ref) fault.Transition {
+	return fault.Lifecycle(s.kind.Provider, s.kind.Name, s.kind.Collection, ref.Tenant)
+}
+
+// scheduleActive lets a resource become active after delay, or end in the
+// error state if it is meant to fail.
+func (s *Store[T]) scheduleActive(key string, version int64, delay time.Duration, fail bool) {
+	if !fail {
+		s.scheduleState(key, version, delay, s.kind.States.Active)
+		return
+	}
+	s.schedule(key, version, delay, func(item *T) bool {
+		previous := s.kind.State(item)
+		s.AddCondition(item, s.kind.States.Error, fmt.Sprintf("%s failed to provision", s.kind.Title), "injectedFault")
+		if s.onState != nil {
+			s.onState(item, previous, s.kind.States.Error)
+		}
+		return true
+	})
+}
+
+func (s *Store[T]) scheduleState(key string, version int64, delay time.Duration, state string) {
+	s.schedule(key, version, delay, func(item *T) bool {
+		s.SetState(item, state)
+		return true
+	})
+}
+
+// schedule runs fn after delay if the resource still has the given version.
+// The resource is removed if fn returns false.
+func (s *Store[T]) schedule(key string, version int64, delay time.Duration, fn func(item *T) bool) {
+	go func() {
+		time.Sleep(delay)
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		item, ok := s.items[key]
+		if !ok {
+			return
+		}
+
+		metadata, ok := s.kind.Metadata(&item)
+		if !ok || metadata.ResourceVersion != version {
+			return
+		}
+
+		if !fn(&item) {
+			s.remove(key)
+			return
+		}
+		s.save(key, item)
+	}()
+}
+
+// save stores an item and persists it. It must be called with the lock held.
+func (s *Store[T]) save(key string, item T) {
+	s.items[key] = item
+
+	raw, err := json.Marshal(item)
+	if err == nil {
+		err = s.backend.Save(s.namespace, key, raw)
+	}
+	if err != nil {
+		log.Printf("saving %s %s failed: %v", s.namespace, key, err)
+	}
+}
+
+// remove deletes an item and its persisted copy. It must be called with the
+// lock held.
+func (s *Store[T]) remove(key string) {
+	delete(s.items, key)
+
+	if err := s.backend.Delete(s.namespace, key); err != nil {
+		log.Printf("deleting %s %s failed: %v", s.namespace, key, err)
+	}
+}
@@ -0,0 +1,361 @@
+package validation
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/pb33f/libopenapi/datamodel/high/base"
+	"go.yaml.in/yaml/v4"
+)
+
+// maxDepth stops the check of recursive schemas.
+const maxDepth = 64
+
+// problem is a part of a request or response that does not match its schema,
+// given by the JSON pointer into the body or the name of a parameter.
+type problem struct {
+	pointer   string
+	parameter string
+	message   string
+}
+
+// field returns the path of the problem like "spec.nics[0].subnetRef".
+func (p problem) field() string {
+	if p.parameter != "" {
+		return p.parameter
+	}
+	var b strings.Builder
+	for _, token := range strings.Split(strings.TrimPrefix(p.pointer, "/"), "/") {
+		if token == "" {
+			continue
+		}
+		token = strings.NewReplacer("~1", "/", "~0", "~").Replace(token)
+		if _, err := strconv.Atoi(token); err == nil {
+			b.WriteString("[" + token + "]")
+			continue
+		}
+		if b.Len() > 0 {
+			b.WriteByte('.')
+		}
+		b.WriteString(token)
+	}
+	return b.String()
+}
+
+func (p problem) String() string {
+	if field := p.field(); field != "" {
+		return field + " " + p.message
+	}
+	return p.message
+}
+
+// checker checks decoded JSON values against schemas. Read-only properties
+// are not required in requests, write-only properties not in responses.
+type checker struct {
+	request bool
+}
+
+func (c *checker) check(schema *base.Schema, value any, pointer string, depth int) []problem {
+	if schema == nil || depth > maxDepth {
+		return nil
+	}
+
+	var problems []problem
+	for _, part := range schema.AllOf {
+		problems = append(problems, c.check(part.Schema(), value, pointer, depth+1)...)
+	}
+	if len(schema.OneOf) > 0 && !c.matchesAny(schema.OneOf, value, pointer, depth) {
+		problems = append(problems, problem{pointer: pointer, message: "does not match any of the allowed schemas"})
+	}
+	if len(schema.AnyOf) > 0 && !c.matchesAny(schema.AnyOf, value, pointer, depth) {
+		problems = append(problems, problem{pointer: pointer, message: "does not match any of the allowed schemas"})
+	}
+
+	if value == nil {
+		if len(schema.Type) > 0 && !hasType(schema, "null") && (schema.Nullable == nil || !*schema.Nullable) {
+			problems = append(problems, problem{pointer: pointer, message: "must not be null"})
+		}
+		return problems
+	}
+	if len(schema.Type) > 0 && !slices.ContainsFunc(schema.Type, func(typ string) bool { return isType(value, typ) }) {
+		return append(problems, problem{pointer: pointer, message: "must be " + typeName(schema)})
+	}
+	if len(schema.Enum) > 0 && !slices.ContainsFunc(schema.Enum, func(node *yaml.Node) bool { return equal(node, value) }) {
+		problems = append(problems, problem{pointer: pointer, message: "must be one of " + enumValues(schema)})
+	}
+
+	switch value := value.(type) {
+	case string:
+		problems = append(problems, checkString(schema, value, pointer)...)
+	case float64:
+		problems = append(problems, checkNumber(schema, value, pointer)...)
+	case []any:
+		if schema.MinItems != nil && int64(len(value)) < *schema.MinItems {
+			problems = append(problems, problem{pointer: pointer, message: fmt.Sprintf("must have at least %d items", *schema.MinItems)})
+		}
+		if schema.MaxItems != nil && int64(len(value)) > *schema.MaxItems {
+			problems = append(problems, problem{pointer: pointer, message: fmt.Sprintf("must have at most %d items", *schema.MaxItems)})
+		}
+		if schema.Items != nil && schema.Items.IsA() {
+			items := schema.Items.A.Schema()
+			for i, item := range value {
+				problems = append(problems, c.check(items, item, pointer+"/"+strconv.Itoa(i), depth+1)...)
+			}
+		}
+	case map[string]any:
+		problems = append(problems, c.checkObject(schema, value, pointer, depth)...)
+	}
+	return problems
+}
+
+func (c *checker) matchesAny(schemas []*base.SchemaProxy, value any, pointer string, depth int) bool {
+	return slices.ContainsFunc(schemas, func(proxy *base.SchemaProxy) bool {
+		return len(c.check(proxy.Schema(), value, pointer, depth+1)) == 0
+	})
+}
+
+func (c *checker) checkObject(schema *base.Schema, object map[string]any, pointer string, depth int) []problem {
+	var problems []problem
+	for _, name := range schema.Required {
+		if _, ok := object[name]; ok {
+			continue
+		}
+		if prop := ownProperty(schema, name); prop != nil {
+			if c.request && prop.ReadOnly != nil && *prop.ReadOnly || !c.request && prop.WriteOnly != nil && *prop.WriteOnly {
+				continue
+			}
+		}
+		problems = append(problems, problem{pointer: pointer + "/" + escape(name), message: "is required"})
+	}
+
+	names := make([]string, 0, len(object))
+	for name := range object {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		if prop := ownProperty(schema, name); prop != nil {
+			problems = append(problems, c.check(prop, object[name], pointer+"/"+escape(name), depth+1)...)
+			continue
+		}
+		additional := schema.AdditionalProperties
+		switch {
+		case additional == nil:
+		case additional.IsB() && !additional.B:
+			problems = append(problems, problem{pointer: pointer + "/" + escape(name), message: "is not allowed"})
+		case additional.IsA() && additional.A != nil:
+			problems = append(problems, c.check(additional.A.Schema(), object[name], pointer+"/"+escape(name), depth+1)...)
+		}
+	}
+	return problems
+}
+
+func checkString(schema *base.Schema, value, pointer string) []problem {
+	var problems []problem
+	length := int64(utf8.RuneCountInString(value))
+	if schema.MinLength != nil && length < *schema.MinLength {
+		problems = append(problems, problem{pointer: pointer, message: fmt.Sprintf("must be at least %d characters long", *schema.MinLength)})
+	}
+	if schema.MaxLength != nil && length > *schema.MaxLength {
+		problems = append(problems, problem{pointer: pointer, message: fmt.Sprintf("must be at most %d characters long", *schema.MaxLength)})
+	}
+	pattern, err := compile(schema.Pattern)
+	if err != nil {
+		problems = append(problems, problem{pointer: pointer, message: "cannot be checked, pattern " + schema.Pattern + " is not supported"})
+	} else if pattern != nil && !pattern.MatchString(value) {
+		problems = append(problems, problem{pointer: pointer, message: "must match " + schema.Pattern})
+	}
+	if schema.Format == "date-time" {
+		if _, err := time.Parse(time.RFC3339, value); err != nil {
+			problems = append(problems, problem{pointer: pointer, message: "must be a RFC 3339 date-time"})
+		}
+	}
+	return problems
+}
+
+func checkNumber(schema *base.Schema, value float64, pointer string) []problem {
+	var problems []problem
+	if schema.Minimum != nil {
+		exclusive := schema.ExclusiveMinimum != nil && schema.ExclusiveMinimum.IsA() && schema.ExclusiveMinimum.A
+		if value < *schema.Minimum || exclusive && value == *schema.Minimum {
+			problems = append(problems, problem{pointer: pointer, message: "must be at least " + formatNumber(*schema.Minimum)})
+		}
+	}
+	if schema.ExclusiveMinimum != nil && schema.ExclusiveMinimum.IsB() && value <= schema.ExclusiveMinimum.B {
+		problems = append(problems, problem{pointer: pointer, message: "must be greater than " + formatNumber(schema.ExclusiveMinimum.B)})
+	}
+	if schema.Maximum != nil {
+		exclusive := schema.ExclusiveMaximum != nil && schema.ExclusiveMaximum.IsA() && schema.ExclusiveMaximum.A
+		if value > *schema.Maximum || exclusive && value == *schema.Maximum {
+			problems = append(problems, problem{pointer: pointer, message: "must be at most " + formatNumber(*schema.Maximum)})
+		}
+	}
+	if schema.ExclusiveMaximum != nil && schema.ExclusiveMaximum.IsB() && value >= schema.ExclusiveMaximum.B {
+		problems = append(problems, problem{pointer: pointer, message: "must be less than " + formatNumber(schema.ExclusiveMaximum.B)})
+	}
+	return problems
+}
+
+// ownProperty returns the schema of a property declared by the schema or the
+// schemas it is composed of with allOf.
+func ownProperty(schema *base.Schema, name string) *base.Schema {
+	if schema == nil {
+		return nil
+	}
+	if schema.Properties != nil {
+		if prop, ok := schema.Properties.Get(name); ok {
+			return prop.Schema()
+		}
+	}
+	for _, part := range schema.AllOf {
+		if prop := ownProperty(part.Schema(), name); prop != nil {
+			return prop
+		}
+	}
+	return nil
+}
+
+func hasType(schema *base.Schema, typ string) bool {
+	return schema != nil && slices.Contains(schema.Type, typ)
+}
+
+func isType(value any, typ string) bool {
+	switch typ {
+	case "object":
+		_, ok := value.(map[string]any)
+		return ok
+	case "array":
+		_, ok := value.([]any)
+		return ok
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "number":
+		_, ok := value.(float64)
+		return ok
+	case "integer":
+		number, ok := value.(float64)
+		return ok && number == math.Trunc(number)
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "null":
+		return value == nil
+	}
+	return true
+}
+
+func typeName(schema *base.Schema) string {
+	if schema == nil || len(schema.Type) == 0 {
+		return "a valid value"
+	}
+	names := make([]string, len(schema.Type))
+	for i, typ := range schema.Type {
+		switch typ {
+		case "array", "integer", "object":
+			names[i] = "an " + typ
+		default:
+			names[i] = "a " + typ
+		}
+	}
+	return strings.Join(names, " or ")
+}
+
+// equal compares an enum value of the spec with a decoded JSON value.
+func equal(node *yaml.Node, value any) bool {
+	var decoded any
+	if err := node.Decode(&decoded); err != nil {
+		return false
+	}
+	raw, err := json.Marshal(decoded)
+	if err != nil {
+		return false
+	}
+	var normalized any
+	if err := json.Unmarshal(raw, &normalized); err != nil {
+		return false
+	}
+	return reflect.DeepEqual(normalized, value)
+}
+
+func enumValues(schema *base.Schema) string {
+	values := make([]string, len(schema.Enum))
+	for i, node := range schema.Enum {
+		values[i] = node.Value
+	}
+	return strings.Join(values, ", ")
+}
+
+func formatNumber(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
+
+func escape(name string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(name)
+}
+
+var patterns sync.Map
+
+type compiledPattern struct {
+	regexp *regexp.Regexp
+	err    error
+}
+
+// compile returns the compiled pattern, or nil if there is none. Patterns Go
+// does not support are an error.
+func compile(pattern string) (*regexp.Regexp, error) {
+	if pattern == "" {
+		return nil, nil
+	}
+	if compiled, ok := patterns.Load(pattern); ok {
+		return compiled.(compiledPattern).regexp, compiled.(compiledPattern).err
+	}
+	compiled, err := regexp.Compile(pattern)
+	patterns.Store(pattern, compiledPattern{regexp: compiled, err: err})
+	return compiled, err
+}
+
+// compilePatterns compiles the patterns of a schema and its inline
+// subschemas. Referenced schemas are compiled where they are declared.
+func compilePatterns(proxy *base.SchemaProxy) error {
+	if proxy == nil || proxy.IsReference() {
+		return nil
+	}
+	schema := proxy.Schema()
+	if schema == nil {
+		return nil
+	}
+	if _, err := compile(schema.Pattern); err != nil {
+		return fmt.Errorf("pattern %s is not supported: %w", schema.Pattern, err)
+	}
+
+	subschemas := slices.Concat(schema.AllOf, schema.OneOf, schema.AnyOf)
+	if schema.Items != nil && schema.Items.IsA() {
+		subschemas = append(subschemas, schema.Items.A)
+	}
+	if schema.AdditionalProperties != nil && schema.AdditionalProperties.IsA() {
+		subschemas = append(subschemas, schema.AdditionalProperties.A)
+	}
+	if schema.Properties != nil {
+		for name, prop := range schema.Properties.FromOldest() {
+			if err := compilePatterns(prop); err != nil {
+				return fmt.Errorf("property %s: %w", name, err)
+			}
+		}
+	}
+	for _, subschema := range subschemas {
+		if err := compilePatterns(subschema); err != nil {
+			return err
+		}
+	}
+	return nil
+}
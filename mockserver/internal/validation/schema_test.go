@@ -0,0 +1,176 @@
+package validation
+
+import (
+	"encoding/json"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/pb33f/libopenapi"
+	"github.com/pb33f/libopenapi/datamodel/high/base"
+)
+
+// testSchema builds the schema given as YAML within an OpenAPI document of
+// the given version.
+func testSchema(t *testing.T, version, schema string) *base.SchemaProxy {
+	t.Helper()
+	indented := "      " + strings.ReplaceAll(strings.TrimSpace(schema), "\n", "\n      ")
+	spec := "openapi: " + version + "\ninfo:\n  title: test\n  version: v1\npaths: {}\ncomponents:\n  schemas:\n    Test:\n" + indented + "\n"
+
+	document, err := libopenapi.NewDocument([]byte(spec))
+	if err != nil {
+		t.Fatal(err)
+	}
+	model, err := document.BuildV3Model()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return model.Model.Components.Schemas.GetOrZero("Test")
+}
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		name    string
+		version string
+		schema  string
+		value   string
+		request bool
+		want    []string
+	}{
+		{name: "type", schema: "type: integer", value: `1`},
+		{name: "wrong type", schema: "type: integer", value: `1.5`, want: []string{"must be an integer"}},
+		{name: "type list", schema: "type: [string, integer]", value: `true`, want: []string{"must be a string or an integer"}},
+		{name: "null", schema: "type: string", value: `null`, want: []string{"must not be null"}},
+		{name: "null type", schema: "type: [string, 'null']", value: `null`},
+		{name: "nullable", version: "3.0.3", schema: "type: string\nnullable: true", value: `null`},
+		{name: "enum", schema: "type: string\nenum: [on, off]", value: `"on"`},
+		{name: "not in enum", schema: "type: string\nenum: [on, off]", value: `"standby"`, want: []string{"must be one of on, off"}},
+		{name: "numeric enum", schema: "type: integer\nenum: [1, 2]", value: `2`},
+		{name: "allOf", schema: "allOf:\n  - type: integer\n  - minimum: 2", value: `1`, want: []string{"must be at least 2"}},
+		{name: "oneOf", schema: "oneOf:\n  - type: string\n  - type: integer", value: `1`},
+		{name: "no oneOf", schema: "oneOf:\n  - type: string\n  - type: integer", value: `true`, want: []string{"does not match any of the allowed schemas"}},
+		{name: "anyOf", schema: "anyOf:\n  - type: string\n  - minimum: 5", value: `7`},
+		{name: "no anyOf", schema: "anyOf:\n  - type: string\n  - minimum: 5", value: `3`, want: []string{"does not match any of the allowed schemas"}},
+		{name: "minItems", schema: "type: array\nminItems: 2", value: `[1]`, want: []string{"must have at least 2 items"}},
+		{name: "maxItems", schema: "type: array\nmaxItems: 1", value: `[1, 2]`, want: []string{"must have at most 1 items"}},
+		{name: "items", schema: "type: array\nitems:\n  type: string", value: `["a", 2]`, want: []string{"[1] must be a string"}},
+		{name: "required", schema: "type: object\nrequired: [name]\nproperties:\n  name:\n    type: string", value: `{}`, want: []string{"name is required"}},
+		{name: "read-only in request", schema: "type: object\nrequired: [id]\nproperties:\n  id:\n    type: string\n    readOnly: true", value: `{}`, request: true},
+		{name: "read-only in response", schema: "type: object\nrequired: [id]\nproperties:\n  id:\n    type: string\n    readOnly: true", value: `{}`, want: []string{"id is required"}},
+		{name: "write-only in response", schema: "type: object\nrequired: [secret]\nproperties:\n  secret:\n    type: string\n    writeOnly: true", value: `{}`},
+		{name: "write-only in request", schema: "type: object\nrequired: [secret]\nproperties:\n  secret:\n    type: string\n    writeOnly: true", value: `{}`, request: true, want: []string{"secret is required"}},
+		{name: "properties", schema: "type: object\nproperties:\n  spec:\n    type: object\n    properties:\n      sizeGB:\n        type: integer", value: `{"spec": {"sizeGB": "10"}}`, want: []string{"spec.sizeGB must be an integer"}},
+		{name: "inherited property", schema: "type: object\nadditionalProperties: false\nallOf:\n  - type: object\n    properties:\n      name:\n        type: string", value: `{"name": "a"}`},
+		{name: "additional properties", schema: "type: object\nproperties:\n  name:\n    type: string", value: `{"other": 1}`},
+		{name: "no additional properties", schema: "type: object\nadditionalProperties: false", value: `{"other": 1}`, want: []string{"other is not allowed"}},
+		{name: "additional properties schema", schema: "type: object\nadditionalProperties:\n  type: string", value: `{"env": "prod", "count": 1}`, want: []string{"count must be a string"}},
+		{name: "minLength", schema: "type: string\nminLength: 3", value: `"äb"`, want: []string{"must be at least 3 characters long"}},
+		{name: "maxLength", schema: "type: string\nmaxLength: 3", value: `"äbc"`},
+		{name: "too long", schema: "type: string\nmaxLength: 3", value: `"abcd"`, want: []string{"must be at most 3 characters long"}},
+		{name: "pattern", schema: "type: string\npattern: '^[a-z]+$'", value: `"abc"`},
+		{name: "no pattern match", schema: "type: string\npattern: '^[a-z]+$'", value: `"ABC"`, want: []string{"must match ^[a-z]+$"}},
+		{name: "unsupported pattern", schema: "type: string\npattern: '^(?!x)'", value: `"abc"`, want: []string{"cannot be checked, pattern ^(?!x) is not supported"}},
+		{name: "date-time", schema: "type: string\nformat: date-time", value: `"2026-10-16T12:00:00Z"`},
+		{name: "no date-time", schema: "type: string\nformat: date-time", value: `"yesterday"`, want: []string{"must be a RFC 3339 date-time"}},
+		{name: "minimum", schema: "type: number\nminimum: 1", value: `1`},
+		{name: "below minimum", schema: "type: number\nminimum: 1", value: `0.5`, want: []string{"must be at least 1"}},
+		{name: "exclusive minimum flag", version: "3.0.3", schema: "type: number\nminimum: 1\nexclusiveMinimum: true", value: `1`, want: []string{"must be at least 1"}},
+		{name: "exclusive minimum", schema: "type: number\nexclusiveMinimum: 1", value: `1`, want: []string{"must be greater than 1"}},
+		{name: "maximum", schema: "type: number\nmaximum: 10", value: `10`},
+		{name: "above maximum", schema: "type: number\nmaximum: 10", value: `11`, want: []string{"must be at most 10"}},
+		{name: "exclusive maximum flag", version: "3.0.3", schema: "type: number\nmaximum: 10\nexclusiveMaximum: true", value: `10`, want: []string{"must be at most 10"}},
+		{name: "exclusive maximum", schema: "type: number\nexclusiveMaximum: 10", value: `10`, want: []string{"must be less than 10"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			version := tt.version
+			if version == "" {
+				version = "3.1.0"
+			}
+			var value any
+			if err := json.Unmarshal([]byte(tt.value), &value); err != nil {
+				t.Fatal(err)
+			}
+
+			problems := (&checker{request: tt.request}).check(testSchema(t, version, tt.schema).Schema(), value, "", 0)
+			got := make([]string, len(problems))
+			for i, p := range problems {
+				got[i] = p.String()
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("check(%s) = %q, want %q", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompilePatterns(t *testing.T) {
+	tests := []struct {
+		name    string
+		schema  string
+		wantErr string
+	}{
+		{name: "supported", schema: "type: string\npattern: '^[a-z]+$'"},
+		{name: "unsupported", schema: "type: string\npattern: '^(?!x)'", wantErr: "pattern ^(?!x) is not supported"},
+		{name: "property", schema: "type: object\nproperties:\n  name:\n    type: string\n    pattern: '\\1'", wantErr: "property name: pattern"},
+		{name: "items", schema: "type: array\nitems:\n  type: string\n  pattern: '(?<=a)b'", wantErr: "pattern (?<=a)b"},
+		{name: "composed", schema: "oneOf:\n  - type: string\n    pattern: '(a'", wantErr: "pattern (a"},
+		{name: "additional properties", schema: "type: object\nadditionalProperties:\n  type: string\n  pattern: 'a++'", wantErr: "pattern a++"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := compilePatterns(testSchema(t, "3.1.0", tt.schema))
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Errorf("compilePatterns() = %v", err)
+			case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Errorf("compilePatterns() = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestProblemField(t *testing.T) {
+	tests := []struct {
+		problem problem
+		want    string
+	}{
+		{problem{}, ""},
+		{problem{pointer: "/spec/nics/0/subnetRef"}, "spec.nics[0].subnetRef"},
+		{problem{pointer: "/labels/app~1name"}, "labels.app/name"},
+		{problem{pointer: "/spec", parameter: "limit"}, "limit"},
+	}
+	for _, tt := range tests {
+		if got := tt.problem.field(); got != tt.want {
+			t.Errorf("field(%+v) = %q, want %q", tt.problem, got, tt.want)
+		}
+	}
+}
+
+func TestParameterValue(t *testing.T) {
+	tests := []struct {
+		schema string
+		raw    []string
+		want   any
+		ok     bool
+	}{
+		{schema: "type: string", raw: []string{"a"}, want: "a", ok: true},
+		{schema: "type: integer", raw: []string{"10"}, want: 10.0, ok: true},
+		{schema: "type: integer", raw: []string{"ten"}},
+		{schema: "type: boolean", raw: []string{"true"}, want: true, ok: true},
+		{schema: "type: array\nitems:\n  type: integer", raw: []string{"1,2"}, want: []any{1.0, 2.0}, ok: true},
+		{schema: "type: array\nitems:\n  type: integer", raw: []string{"1", "x"}},
+	}
+	for _, tt := range tests {
+		got, ok := parameterValue(testSchema(t, "3.1.0", tt.schema).Schema(), tt.raw)
+		if ok != tt.ok || ok && !equalJSON(got, tt.want) {
+			t.Errorf("parameterValue(%s, %q) = %v, %v, want %v, %v", tt.schema, tt.raw, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func equalJSON(a, b any) bool {
+	rawA, _ := json.Marshal(a)
+	rawB, _ := json.Marshal(b)
+	return string(rawA) == string(rawB)
+}
@@ -0,0 +1,448 @@
+package validation
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pb33f/libopenapi"
+	"github.com/pb33f/libopenapi/datamodel"
+	"github.com/pb33f/libopenapi/datamodel/high/base"
+	v3high "github.com/pb33f/libopenapi/datamodel/high/v3"
+	"github.com/pb33f/libopenapi/orderedmap"
+)
+
+// route is an operation of the specs and the path it is served under, split
+// into segments like "providers", "seca.storage", "v1", "tenants", "{tenant}".
+type route struct {
+	method    string
+	segments  []string
+	operation *v3high.Operation
+	params    []*v3high.Parameter
+}
+
+// Validator checks requests and responses of the provider APIs against the
+// SecAPI specs.
+type Validator struct {
+	routes []route
+}
+
+// ErrNoSpecs is returned by Load if a directory holds no operations to
+// validate against.
+var ErrNoSpecs = errors.New("no operations found")
+
+// Load reads all specs in a directory, like ext/secapi/spec. Patterns in the
+// specs that Go does not support are an error.
+func Load(dir string) (*Validator, error) {
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	v := &Validator{}
+	for _, file := range files {
+		if file.IsDir() || !strings.HasSuffix(file.Name(), ".yaml") {
+			continue
+		}
+		if err := v.load(filepath.Join(dir, file.Name())); err != nil {
+			return nil, fmt.Errorf("loading spec %s: %w", file.Name(), err)
+		}
+	}
+	if len(v.routes) == 0 {
+		return nil, fmt.Errorf("%w in %s", ErrNoSpecs, dir)
+	}
+	return v, nil
+}
+
+func (v *Validator) load(path string) error {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	document, err := libopenapi.NewDocumentWithConfiguration(raw, &datamodel.DocumentConfiguration{
+		BasePath:            filepath.Dir(path),
+		AllowFileReferences: true,
+	})
+	if err != nil {
+		return err
+	}
+	model, err := document.BuildV3Model()
+	if err != nil {
+		return err
+	}
+	spec := model.Model
+	if err := compileSpecPatterns(&spec); err != nil {
+		return err
+	}
+	if spec.Paths == nil || spec.Paths.PathItems == nil {
+		return nil
+	}
+
+	basePath := ""
+	if len(spec.Servers) > 0 {
+		uri, err := url.Parse(spec.Servers[0].URL)
+		if err != nil {
+			return err
+		}
+		basePath = strings.TrimSuffix(uri.Path, "/")
+	}
+	for path, item := range spec.Paths.PathItems.FromOldest() {
+		for method, op := range item.GetOperations().FromOldest() {
+			v.routes = append(v.routes, route{
+				method:    strings.ToUpper(method),
+				segments:  strings.Split(strings.Trim(basePath+path, "/"), "/"),
+				operation: op,
+				params:    append(append([]*v3high.Parameter(nil), item.Parameters...), op.Parameters...),
+			})
+		}
+	}
+	return nil
+}
+
+// compileSpecPatterns compiles the patterns of the component schemas and the
+// inline schemas of the operations.
+func compileSpecPatterns(spec *v3high.Document) error {
+	if spec.Components != nil && spec.Components.Schemas != nil {
+		for name, schema := range spec.Components.Schemas.FromOldest() {
+			if err := compilePatterns(schema); err != nil {
+				return fmt.Errorf("schema %s: %w", name, err)
+			}
+		}
+	}
+	if spec.Paths == nil || spec.Paths.PathItems == nil {
+		return nil
+	}
+	for path, item := range spec.Paths.PathItems.FromOldest() {
+		for method, op := range item.GetOperations().FromOldest() {
+			if err := compileOperationPatterns(item, op); err != nil {
+				return fmt.Errorf("%s %s: %w", strings.ToUpper(method), path, err)
+			}
+		}
+	}
+	return nil
+}
+
+func compileOperationPatterns(item *v3high.PathItem, op *v3high.Operation) error {
+	for _, param := range append(append([]*v3high.Parameter(nil), item.Parameters...), op.Parameters...) {
+		if err := compilePatterns(param.Schema); err != nil {
+			return fmt.Errorf("parameter %s: %w", param.Name, err)
+		}
+	}
+	var contents []*orderedmap.Map[string, *v3high.MediaType]
+	if op.RequestBody != nil {
+		contents = append(contents, op.RequestBody.Content)
+	}
+	if op.Responses != nil {
+		if op.Responses.Default != nil {
+			contents = append(contents, op.Responses.Default.Content)
+		}
+		if op.Responses.Codes != nil {
+			for _, response := range op.Responses.Codes.FromOldest() {
+				contents = append(contents, response.Content)
+			}
+		}
+	}
+	for _, content := range contents {
+		if content == nil {
+			continue
+		}
+		for _, media := range content.FromOldest() {
+			if err := compilePatterns(media.Schema); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// match finds the route of a request and the values of its path parameters.
+// Literal segments win over parameters, so ".../skus" does not match
+// ".../{name}".
+func (v *Validator) match(method, path string) (*route, map[string]string) {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+
+	var best *route
+	bestLiterals := -1
+	for i := range v.routes {
+		r := &v.routes[i]
+		if r.method != method || len(r.segments) != len(segments) {
+			continue
+		}
+		literals := 0
+		matches := true
+		for j, segment := range r.segments {
+			if strings.HasPrefix(segment, "{") {
+				continue
+			}
+			if segment != segments[j] {
+				matches = false
+				break
+			}
+			literals++
+		}
+		if matches && literals > bestLiterals {
+			best, bestLiterals = r, literals
+		}
+	}
+	if best == nil {
+		return nil, nil
+	}
+
+	values := map[string]string{}
+	for j, segment := range best.segments {
+		if strings.HasPrefix(segment, "{") {
+			values[strings.Trim(segment, "{}")], _ = url.PathUnescape(segments[j])
+		}
+	}
+	return best, values
+}
+
+// Validate rejects requests that do not match the specs with 400. In strict
+// mode the responses are checked as well, answering with 500 instead of a
+// response that does not match. Requests to paths outside the specs, like
+// the admin API, pass unchecked.
+func (v *Validator) Validate(strict bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		r, pathValues := v.match(c.Request.Method, c.Request.URL.Path)
+		if r == nil {
+			c.Next()
+			return
+		}
+
+		problems, err := r.validateRequest(c.Request, pathValues)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if len(problems) > 0 {
+			c.Abort()
+			respond(c, http.StatusBadRequest, "request does not match the API specification", problems)
+			return
+		}
+		if !strict {
+			c.Next()
+			return
+		}
+
+		writer := &bufferedWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+		c.Next()
+		c.Writer = writer.ResponseWriter
+
+		if problems := r.validateResponse(writer.Status(), writer.Header().Get("Content-Type"), writer.body.Bytes()); len(problems) > 0 {
+			log.Printf("%s %s: response %d does not match the API specification: %s", c.Request.Method, c.Request.URL.Path, writer.Status(), summary(problems))
+			writer.Header().Del("Content-Length")
+			respond(c, http.StatusInternalServerError, "response does not match the API specification", problems)
+			return
+		}
+		if writer.body.Len() > 0 {
+			_, _ = writer.ResponseWriter.Write(writer.body.Bytes())
+			return
+		}
+		writer.ResponseWriter.WriteHeaderNow()
+	}
+}
+
+func (r *route) validateRequest(req *http.Request, pathValues map[string]string) ([]problem, error) {
+	var problems []problem
+	query := req.URL.Query()
+	for _, param := range r.params {
+		if param.Schema == nil {
+			continue
+		}
+		var raw []string
+		switch param.In {
+		case "path":
+			if value, ok := pathValues[param.Name]; ok {
+				raw = []string{value}
+			}
+		case "query":
+			raw = query[param.Name]
+		default:
+			continue
+		}
+
+		if len(raw) == 0 {
+			if param.Required != nil && *param.Required {
+				problems = append(problems, problem{parameter: param.Name, message: "is required"})
+			}
+			continue
+		}
+		schema := param.Schema.Schema()
+		value, ok := parameterValue(schema, raw)
+		if !ok {
+			problems = append(problems, problem{parameter: param.Name, message: "must be " + typeName(schema)})
+			continue
+		}
+		for _, p := range (&checker{request: true}).check(schema, value, "", 0) {
+			p.parameter = param.Name
+			problems = append(problems, p)
+		}
+	}
+
+	body := r.operation.RequestBody
+	if body == nil || body.Content == nil {
+		return problems, nil
+	}
+	media := mediaType(body.Content, req.Header.Get("Content-Type"))
+	if media == nil || media.Schema == nil {
+		return problems, nil
+	}
+
+	raw, err := io.ReadAll(req.Body)
+	if err != nil {
+		return nil, err
+	}
+	req.Body = io.NopCloser(bytes.NewReader(raw))
+	if len(bytes.TrimSpace(raw)) == 0 {
+		if body.Required != nil && *body.Required {
+			problems = append(problems, problem{message: "request body is required"})
+		}
+		return problems, nil
+	}
+
+	var document any
+	if err := json.Unmarshal(raw, &document); err != nil {
+		return append(problems, problem{message: "request body is not valid JSON: " + err.Error()}), nil
+	}
+	return append(problems, (&checker{request: true}).check(media.Schema.Schema(), document, "", 0)...), nil
+}
+
+func (r *route) validateResponse(status int, contentType string, body []byte) []problem {
+	if r.operation.Responses == nil {
+		return nil
+	}
+	response := r.operation.Responses.Default
+	if r.operation.Responses.Codes != nil {
+		if coded := r.operation.Responses.Codes.GetOrZero(strconv.Itoa(status)); coded != nil {
+			response = coded
+		}
+	}
+	if response == nil {
+		return []problem{{message: "status " + strconv.Itoa(status) + " is not specified"}}
+	}
+	if response.Content == nil || response.Content.Len() == 0 {
+		return nil
+	}
+
+	media := mediaType(response.Content, contentType)
+	if media == nil || media.Schema == nil {
+		return nil
+	}
+	var document any
+	if err := json.Unmarshal(body, &document); err != nil {
+		return []problem{{message: "response body is not valid JSON: " + err.Error()}}
+	}
+	return (&checker{}).check(media.Schema.Schema(), document, "", 0)
+}
+
+// mediaType picks the content of a request body or response matching the
+// content type, falling back to JSON.
+func mediaType(content *orderedmap.Map[string, *v3high.MediaType], contentType string) *v3high.MediaType {
+	if mediaType, _, err := mime.ParseMediaType(contentType); err == nil {
+		if media := content.GetOrZero(mediaType); media != nil {
+			return media
+		}
+	}
+	for _, fallback := range []string{"application/json", "application/problem+json"} {
+		if media := content.GetOrZero(fallback); media != nil {
+			return media
+		}
+	}
+	return nil
+}
+
+// parameterValue converts the raw values of a path or query parameter to the
+// type of its schema.
+func parameterValue(schema *base.Schema, raw []string) (any, bool) {
+	if schema == nil {
+		return raw[0], true
+	}
+	switch {
+	case hasType(schema, "array"):
+		if len(raw) == 1 {
+			raw = strings.Split(raw[0], ",")
+		}
+		var items *base.Schema
+		if schema.Items != nil && schema.Items.IsA() {
+			items = schema.Items.A.Schema()
+		}
+		values := make([]any, len(raw))
+		for i, value := range raw {
+			converted, ok := parameterValue(items, []string{value})
+			if !ok {
+				return nil, false
+			}
+			values[i] = converted
+		}
+		return values, true
+	case hasType(schema, "integer"), hasType(schema, "number"):
+		value, err := strconv.ParseFloat(raw[0], 64)
+		return value, err == nil
+	case hasType(schema, "boolean"):
+		value, err := strconv.ParseBool(raw[0])
+		return value, err == nil
+	}
+	return raw[0], true
+}
+
+// bufferedWriter holds back the response until it has been validated.
+type bufferedWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *bufferedWriter) Write(data []byte) (int, error) {
+	return w.body.Write(data)
+}
+
+func (w *bufferedWriter) WriteString(s string) (int, error) {
+	return w.body.WriteString(s)
+}
+
+func (w *bufferedWriter) WriteHeaderNow() {}
+
+// respond answers with problem details listing the problems by their field
+// path and JSON pointer.
+func respond(c *gin.Context, status int, detail string, problems []problem) {
+	sources := make([]gin.H, len(problems))
+	fields := make([]gin.H, len(problems))
+	for i, p := range problems {
+		source := gin.H{"detail": p.message}
+		if p.parameter != "" {
+			source["parameter"] = p.parameter
+		} else {
+			source["pointer"] = p.pointer
+		}
+		sources[i] = source
+		fields[i] = gin.H{"field": p.field(), "message": p.message}
+	}
+	c.Header("Content-Type", "application/problem+json")
+	c.JSON(status, gin.H{
+		"status":  status,
+		"title":   http.StatusText(status),
+		"detail":  detail,
+		"error":   detail + ": " + summary(problems),
+		"sources": sources,
+		"errors":  fields,
+	})
+}
+
+func summary(problems []problem) string {
+	messages := make([]string, len(problems))
+	for i, p := range problems {
+		messages[i] = p.String()
+	}
+	return strings.Join(messages, "; ")
+}
@@ -0,0 +1,151 @@
+package validation
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+const testSpec = `openapi: 3.1.0
+info:
+  title: test
+  version: v1
+servers:
+  - url: /providers/seca.storage/v1
+paths:
+  /tenants/{tenant}/block-storages/{name}:
+    parameters:
+      - name: tenant
+        in: path
+        required: true
+        schema:
+          type: string
+          pattern: '^[a-z]+$'
+      - name: name
+        in: path
+        required: true
+        schema:
+          type: string
+    put:
+      parameters:
+        - name: limit
+          in: query
+          schema:
+            type: integer
+            minimum: 1
+      requestBody:
+        required: true
+        content:
+          application/json:
+            schema:
+              $ref: '#/components/schemas/BlockStorage'
+      responses:
+        '200':
+          content:
+            application/json:
+              schema:
+                $ref: '#/components/schemas/BlockStorage'
+components:
+  schemas:
+    BlockStorage:
+      type: object
+      required: [spec]
+      properties:
+        spec:
+          type: object
+          required: [sizeGB]
+          properties:
+            sizeGB:
+              type: integer
+`
+
+func writeSpec(t *testing.T, spec string) string {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "seca.storage.v1.yaml"), []byte(spec), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func TestLoad(t *testing.T) {
+	if _, err := Load(filepath.Join(t.TempDir(), "missing")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Load(missing) = %v, want %v", err, os.ErrNotExist)
+	}
+	if _, err := Load(t.TempDir()); !errors.Is(err, ErrNoSpecs) {
+		t.Errorf("Load(empty) = %v, want %v", err, ErrNoSpecs)
+	}
+
+	unsupported := strings.Replace(testSpec, "'^[a-z]+$'", "'^(?!admin)'", 1)
+	_, err := Load(writeSpec(t, unsupported))
+	if err == nil || !strings.Contains(err.Error(), "parameter tenant: pattern ^(?!admin) is not supported") {
+		t.Errorf("Load(unsupported pattern) = %v", err)
+	}
+
+	v, err := Load(writeSpec(t, testSpec))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(v.routes) != 1 {
+		t.Errorf("routes = %d, want 1", len(v.routes))
+	}
+}
+
+func TestValidate(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	v, err := Load(writeSpec(t, testSpec))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		strict     bool
+		method     string
+		path       string
+		body       string
+		response   string
+		wantStatus int
+		wantError  string
+	}{
+		{name: "valid", method: http.MethodPut, path: "/providers/seca.storage/v1/tenants/t/block-storages/b", body: `{"spec": {"sizeGB": 10}}`, wantStatus: http.StatusOK},
+		{name: "invalid body", method: http.MethodPut, path: "/providers/seca.storage/v1/tenants/t/block-storages/b", body: `{"spec": {"sizeGB": "10"}}`, wantStatus: http.StatusBadRequest, wantError: "spec.sizeGB must be an integer"},
+		{name: "missing body", method: http.MethodPut, path: "/providers/seca.storage/v1/tenants/t/block-storages/b", wantStatus: http.StatusBadRequest, wantError: "request body is required"},
+		{name: "invalid path parameter", method: http.MethodPut, path: "/providers/seca.storage/v1/tenants/T1/block-storages/b", body: `{"spec": {"sizeGB": 10}}`, wantStatus: http.StatusBadRequest, wantError: "tenant must match"},
+		{name: "invalid query parameter", method: http.MethodPut, path: "/providers/seca.storage/v1/tenants/t/block-storages/b?limit=0", body: `{"spec": {"sizeGB": 10}}`, wantStatus: http.StatusBadRequest, wantError: "limit must be at least 1"},
+		{name: "outside the specs", method: http.MethodGet, path: "/_admin/state", wantStatus: http.StatusOK},
+		{name: "invalid response", strict: true, method: http.MethodPut, path: "/providers/seca.storage/v1/tenants/t/block-storages/b", body: `{"spec": {"sizeGB": 10}}`, response: `{"spec": {}}`, wantStatus: http.StatusInternalServerError, wantError: "spec.sizeGB is required"},
+		{name: "invalid response not checked", method: http.MethodPut, path: "/providers/seca.storage/v1/tenants/t/block-storages/b", body: `{"spec": {"sizeGB": 10}}`, response: `{"spec": {}}`, wantStatus: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response := tt.response
+			if response == "" {
+				response = tt.body
+			}
+			router := gin.New()
+			router.Use(v.Validate(tt.strict))
+			router.NoRoute(func(c *gin.Context) {
+				c.Data(http.StatusOK, "application/json", []byte(response))
+			})
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+			if tt.wantError != "" && !strings.Contains(w.Body.String(), tt.wantError) {
+				t.Errorf("body = %s, want it to name %q", w.Body, tt.wantError)
+			}
+		})
+	}
+}
@@ -13,22 +13,115 @@ import (
 	"syscall"
 	"time"
 
+	k_v1beta1 "cape-project.eu/mockserver/extensions/kubernetes/v1beta1"
+	a_v1 "cape-project.eu/mockserver/foundation/authorization/v1"
 	c_v1 "cape-project.eu/mockserver/foundation/compute/v1"
+	n_v1 "cape-project.eu/mockserver/foundation/network/v1"
+	r_v1 "cape-project.eu/mockserver/foundation/region/v1"
 	s_v1 "cape-project.eu/mockserver/foundation/storage/v1"
-	ws_v1 "cape-project.eu/mockserver/foundation/workspace/v1"
+	"cape-project.eu/mockserver/internal/admin"
+	"cape-project.eu/mockserver/internal/auth"
+	"cape-project.eu/mockserver/internal/fault"
+	"cape-project.eu/mockserver/internal/region"
+	"cape-project.eu/mockserver/internal/store"
+	"cape-project.eu/mockserver/internal/validation"
 	"github.com/gin-gonic/gin"
 )
 
 func main() {
 	var port int
+	var authToken string
+	var enforcePermissions bool
+	var regionsFile string
+	var storage string
+	var storageFile string
+	var fixturesFile string
+	var faultsFile string
+	var specDir string
+	var validationMode string
 	flag.IntVar(&port, "port", resolvePort(), "server port")
+	flag.StringVar(&authToken, "auth-token", os.Getenv("AUTH_TOKEN"), "bearer token required on every request (disabled if empty)")
+	flag.BoolVar(&enforcePermissions, "enforce-permissions", os.Getenv("ENFORCE_PERMISSIONS") == "true", "only allow requests granted to the caller by a role assignment, the auth token acts as admin")
+	flag.StringVar(&regionsFile, "regions", os.Getenv("REGIONS_FILE"), "YAML or JSON file with the region catalog (built-in catalog if empty)")
+	flag.StringVar(&storage, "storage", envOrDefault("STORAGE", "memory"), "storage backend for the mocked resources: memory or file")
+	flag.StringVar(&storageFile, "storage-file", envOrDefault("STORAGE_FILE", "mockserver-state.json"), "state file of the file storage backend")
+	flag.StringVar(&fixturesFile, "fixtures", os.Getenv("FIXTURES_FILE"), "YAML or JSON file with resources to seed on start")
+	flag.StringVar(&faultsFile, "faults", os.Getenv("FAULTS_FILE"), "YAML or JSON file with fault injection rules")
+	flag.StringVar(&specDir, "spec", envOrDefault("SPEC_DIR", "../ext/secapi/spec"), "directory with the SecAPI specs to validate against")
+	flag.StringVar(&validationMode, "validation", envOrDefault("VALIDATION", "requests"), "validation against the specs: off, requests or strict (requests and responses)")
 	flag.Parse()
 
+	backend, err := store.Open(storage, storageFile)
+	if err != nil {
+		log.Fatalf("opening storage failed: %v", err)
+	}
+	store.Configure(backend)
+
+	if regionsFile != "" {
+		regions, err := region.Load(regionsFile)
+		if err != nil {
+			log.Fatalf("loading regions failed: %v", err)
+		}
+		region.Configure(regions)
+	}
+
+	if faultsFile != "" {
+		rules, err := fault.Load(faultsFile)
+		if err != nil {
+			log.Fatalf("loading fault rules failed: %v", err)
+		}
+		fault.Configure(rules)
+	}
+
+	var validator *validation.Validator
+	switch validationMode {
+	case "off":
+	case "requests", "strict":
+		validator, err = validation.Load(specDir)
+		if (errors.Is(err, os.ErrNotExist) || errors.Is(err, validation.ErrNoSpecs)) && validationMode == "requests" {
+			log.Printf("no specs found in %s, requests are not validated", specDir)
+		} else if err != nil {
+			log.Fatalf("loading specs failed: %v", err)
+		}
+	default:
+		log.Fatalf("unknown validation mode %q", validationMode)
+	}
+
+	if enforcePermissions && authToken == "" {
+		log.Fatal("enforcing permissions requires an auth token")
+	}
+
+	authorization := a_v1.NewServer()
+
 	router := gin.Default()
+	switch {
+	case enforcePermissions:
+		router.Use(authorization.EnforcePermissions(authToken))
+	case authToken != "":
+		router.Use(auth.RequireBearerToken(authToken))
+	}
+	router.Use(fault.Inject())
+	if validator != nil {
+		router.Use(validator.Validate(validationMode == "strict"))
+	}
 
-	ws_v1.RegisterServer(router)
 	s_v1.RegisterServer(router)
 	c_v1.RegisterServer(router)
+	n_v1.RegisterServer(router)
+	a_v1.RegisterServer(router, authorization)
+	r_v1.RegisterServer(router)
+	k_v1beta1.RegisterServer(router)
+	registerGeneratedServers(router)
+	admin.RegisterServer(router)
+	fault.RegisterServer(router)
+
+	if fixturesFile != "" {
+		count, err := admin.SeedFile(fixturesFile)
+		if err != nil {
+			log.Fatalf("seeding fixtures failed: %v", err)
+		}
+		log.Printf("seeded %d fixtures from %s", count, fixturesFile)
+	}
 
 	addr := net.JoinHostPort("", strconv.Itoa(port))
 	server := &http.Server{
@@ -72,3 +165,10 @@ func resolvePort() int {
 
 	return port
 }
+
+func envOrDefault(name, fallback string) string {
+	if value := os.Getenv(name); value != "" {
+		return value
+	}
+	return fallback
+}
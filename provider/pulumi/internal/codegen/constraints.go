@@ -0,0 +1,98 @@
+package codegen
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/pb33f/libopenapi/datamodel/high/base"
+)
+
+// ConstraintLines collects the OpenAPI validation keywords of a property and
+// its nested properties as utils.Constraint expressions. Nested paths use the
+// property names as they appear in the pulumi tags of the schema types. Refs
+// already visited on the current branch are skipped to avoid cycles, patterns
+// Go cannot compile are skipped with a warning.
+func ConstraintLines(path string, schemaProxy *base.SchemaProxy, resolver *SchemaResolver) []string {
+	return constraintLines(path, schemaProxy, resolver, map[string]bool{})
+}
+
+func constraintLines(path string, schemaProxy *base.SchemaProxy, resolver *SchemaResolver, visited map[string]bool) []string {
+	if schemaProxy == nil {
+		return nil
+	}
+	schema := schemaProxy.Schema()
+	if schemaProxy.IsReference() {
+		refName := RefToSchemaName(schemaProxy.GetReference())
+		if visited[refName] {
+			return nil
+		}
+		visited[refName] = true
+		defer delete(visited, refName)
+		if ref := resolver.Lookup(refName); ref != nil {
+			schema = ref.Schema()
+		}
+	}
+	if schema == nil {
+		return nil
+	}
+
+	lines := make([]string, 0)
+	if schema.Minimum != nil {
+		lines = append(lines, fmt.Sprintf("utils.Minimum(%q, %v)", path, *schema.Minimum))
+	}
+	if schema.Maximum != nil {
+		lines = append(lines, fmt.Sprintf("utils.Maximum(%q, %v)", path, *schema.Maximum))
+	}
+	if schema.MinLength != nil {
+		lines = append(lines, fmt.Sprintf("utils.MinLength(%q, %d)", path, *schema.MinLength))
+	}
+	if schema.MaxLength != nil {
+		lines = append(lines, fmt.Sprintf("utils.MaxLength(%q, %d)", path, *schema.MaxLength))
+	}
+	if schema.Pattern != "" {
+		if _, err := regexp.Compile(schema.Pattern); err != nil {
+			fmt.Printf("warning: skipping pattern of %s, it is not valid RE2 syntax: %v\n", path, err)
+		} else {
+			lines = append(lines, fmt.Sprintf("utils.Pattern(%q, %q)", path, schema.Pattern))
+		}
+	}
+	if len(schema.Enum) > 0 {
+		values := make([]string, 0, len(schema.Enum))
+		for _, node := range schema.Enum {
+			if node != nil {
+				values = append(values, fmt.Sprintf("%q", node.Value))
+			}
+		}
+		lines = append(lines, fmt.Sprintf("utils.Enum(%q, %s)", path, strings.Join(values, ", ")))
+	}
+
+	nested := func(path string, schemaProxy *base.SchemaProxy) {
+		lines = append(lines, constraintLines(path, schemaProxy, resolver, visited)...)
+	}
+
+	for _, allOf := range schema.AllOf {
+		nested(path, allOf)
+	}
+
+	required := map[string]bool{}
+	for _, name := range schema.Required {
+		required[name] = true
+	}
+	if schema.Properties != nil {
+		for propName, propSchema := range schema.Properties.FromOldest() {
+			propPath := path + "." + propName
+			if _, hasDefault := DefaultValueLiteral(propSchema); required[propName] && !hasDefault {
+				lines = append(lines, fmt.Sprintf("utils.Required(%q)", propPath))
+			}
+			nested(propPath, propSchema)
+		}
+	}
+	if schema.Items != nil && schema.Items.IsA() {
+		nested(path+"[*]", schema.Items.A)
+	}
+	if schema.AdditionalProperties != nil && schema.AdditionalProperties.IsA() {
+		nested(path+"[*]", schema.AdditionalProperties.A)
+	}
+	return lines
+}
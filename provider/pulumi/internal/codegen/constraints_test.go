@@ -0,0 +1,95 @@
+package codegen
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+const constraintSchemas = `
+components:
+  schemas:
+    Volume:
+      type: object
+      required: [spec]
+      properties:
+        spec:
+          $ref: "#/components/schemas/VolumeSpec"
+    VolumeSpec:
+      type: object
+      required: [sizeGB, tier]
+      properties:
+        sizeGB:
+          type: integer
+          minimum: 1
+          maximum: 1024
+        tier:
+          type: string
+          enum: [standard, premium]
+        storage_class:
+          type: string
+          default: ssd
+          minLength: 2
+          maxLength: 16
+          pattern: "^[a-z]+$"
+        tags:
+          type: array
+          items:
+            type: string
+            maxLength: 8
+        parent:
+          $ref: "#/components/schemas/VolumeSpec"
+    Invalid:
+      type: object
+      properties:
+        name:
+          type: string
+          maxLength: 63
+          pattern: "^(?!reserved).*$"
+`
+
+func loadConstraintSchemas(t *testing.T) *SchemaResolver {
+	t.Helper()
+	file := filepath.Join(t.TempDir(), "schemas.yaml")
+	if err := os.WriteFile(file, []byte(constraintSchemas), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	model, err := BuildV3Model(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewSchemaResolver([]ModelEntry{{Path: file, Model: model}})
+}
+
+func TestConstraintLines(t *testing.T) {
+	resolver := loadConstraintSchemas(t)
+
+	spec := resolver.Lookup("Volume").Schema().Properties.GetOrZero("spec")
+	lines := ConstraintLines("spec", spec, resolver)
+	want := []string{
+		`utils.Required("spec.sizeGB")`,
+		`utils.Minimum("spec.sizeGB", 1)`,
+		`utils.Maximum("spec.sizeGB", 1024)`,
+		`utils.Required("spec.tier")`,
+		`utils.Enum("spec.tier", "standard", "premium")`,
+		`utils.MinLength("spec.storage_class", 2)`,
+		`utils.MaxLength("spec.storage_class", 16)`,
+		`utils.Pattern("spec.storage_class", "^[a-z]+$")`,
+		`utils.MaxLength("spec.tags[*]", 8)`,
+	}
+	if !reflect.DeepEqual(lines, want) {
+		t.Errorf("ConstraintLines() =\n%v\nwant\n%v", lines, want)
+	}
+}
+
+func TestConstraintLinesInvalidPattern(t *testing.T) {
+	resolver := loadConstraintSchemas(t)
+
+	// Lookarounds are not supported by Go, so only the pattern is skipped.
+	lines := ConstraintLines("invalid", resolver.Lookup("Invalid"), resolver)
+	want := []string{`utils.MaxLength("invalid.name", 63)`}
+	if !reflect.DeepEqual(lines, want) {
+		t.Errorf("ConstraintLines() = %v, want %v", lines, want)
+	}
+}
@@ -49,13 +49,15 @@ var tmplFuncMap = template.FuncMap{
 	"lower":      strings.ToLower,
 	"upper":      strings.ToUpper,
 	"pascalCase": PascalCase,
-	"camelCase": func(s string) string {
-		if isUpper(s) || isLower(s) {
-			return strings.ToLower(s)
-		}
-		return LowerCamel(PascalCase(s))
-	},
-	"add": func(a, b int) int { return a + b },
+	"camelCase":  CamelCase,
+	"add":        func(a, b int) int { return a + b },
+}
+
+func CamelCase(s string) string {
+	if isUpper(s) || isLower(s) {
+		return strings.ToLower(s)
+	}
+	return LowerCamel(PascalCase(s))
 }
 
 func isUpper(s string) bool {
@@ -97,15 +99,17 @@ type ApiFunctionOverwrites struct {
 }
 
 type ControlResourceSpec struct {
-	Package               string                 `yaml:"package"`
-	APIPackage            string                 `yaml:"apiPackage"`
-	WithoutWorkspace      bool                   `yaml:"withoutWorkspace"`
-	WithCustomGenerators  bool                   `yaml:"withCustomGenerators"`
-	ExtraPaths            []string               `yaml:"extraPaths"`
-	Input                 []InOutSpec            `yaml:"input"`
-	Output                []InOutSpec            `yaml:"output"`
+	Package                 string                 `yaml:"package"`
+	APIPackage              string                 `yaml:"apiPackage"`
+	WithoutWorkspace        bool                   `yaml:"withoutWorkspace"`
+	WithCustomGenerators    bool                   `yaml:"withCustomGenerators"`
+	ExtraPaths              []string               `yaml:"extraPaths"`
+	Input                   []InOutSpec            `yaml:"input"`
+	Output                  []InOutSpec            `yaml:"output"`
 	ApiFunctionOverwrites   *ApiFunctionOverwrites `yaml:"apiFunctionOverwrites,omitempty"`
 	ProviderPrefixOverwrite *string                `yaml:"providerPrefixOverwrite,omitempty"`
+	ReplaceOnChanges        []string               `yaml:"replaceOnChanges,omitempty"`
+	LookupFunction          string                 `yaml:"lookupFunction,omitempty"`
 }
 
 type ProviderGetterFunction struct {
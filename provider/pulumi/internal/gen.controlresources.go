@@ -25,8 +25,11 @@ var createTemplate = codegen.ReadTemplate("create", "codegen/create.tmpl")
 var readTemplate = codegen.ReadTemplate("read", "codegen/read.tmpl")
 var updateTemplate = codegen.ReadTemplate("update", "codegen/update.tmpl")
 var deleteTemplate = codegen.ReadTemplate("delete", "codegen/delete.tmpl")
+var diffTemplate = codegen.ReadTemplate("diff", "codegen/diff.tmpl")
+var checkTemplate = codegen.ReadTemplate("check", "codegen/check.tmpl")
 var apiTemplate = codegen.ReadTemplate("api", "codegen/api.tmpl")
 var converterTemplate = codegen.ReadTemplate("converter", "codegen/converter.tmpl")
+var lookupTemplate = codegen.ReadTemplate("lookup", "codegen/lookup.tmpl")
 
 func main() {
 	cwd, _ := os.Getwd()
@@ -85,6 +88,14 @@ func main() {
 		outPath = filepath.Join(outDir, fileName)
 		writeTemplate(outPath, def, deleteTemplate)
 
+		fileName = fmt.Sprintf("%s.diff.gen.go", strings.ToLower(name))
+		outPath = filepath.Join(outDir, fileName)
+		writeTemplate(outPath, def, diffTemplate)
+
+		fileName = fmt.Sprintf("%s.check.gen.go", strings.ToLower(name))
+		outPath = filepath.Join(outDir, fileName)
+		writeTemplate(outPath, def, checkTemplate)
+
 		fileName = fmt.Sprintf("%s.api.gen.go", strings.ToLower(name))
 		outPath = filepath.Join(outDir, fileName)
 		writeTemplate(outPath, def, apiTemplate)
@@ -94,11 +105,18 @@ func main() {
 			outPath = filepath.Join(outDir, fileName)
 			writeTemplate(outPath, def, converterTemplate)
 		}
+
+		if def.LookupFunction != "" {
+			fileName = fmt.Sprintf("%s.lookup.gen.go", strings.ToLower(name))
+			outPath = filepath.Join(outDir, fileName)
+			writeTemplate(outPath, def, lookupTemplate)
+		}
 	}
 }
 
 type resourceField struct {
 	Name       string
+	Property   string
 	Type       string
 	Tag        string
 	Desc       string
@@ -121,15 +139,22 @@ type resourceDef struct {
 	ResourceDesc         string
 	ArgsAnnotateLines    []string
 	StateAnnotateLines   []string
+	ResultAnnotateLines  []string
 	GetFn                   string
 	CreateFn                string
 	UpdateFn                string
 	DeleteFn                string
 	ProviderPrefixOverwrite *string
+	UpdateParams            string
+	DeleteParams            string
+	ReplacePaths            []string
+	Constraints             []string
+	LookupFunction          string
 }
 
 func buildResourceDef(name string, spec codegen.ControlResourceSpec, resolver *codegen.SchemaResolver) resourceDef {
 	inputs := make([]resourceField, 0, len(spec.Input))
+	constraints := make([]string, 0)
 	for _, input := range spec.Input {
 		fieldName := input.Name
 		hasOverride := input.Type != "" || input.Description != "" || input.Default != nil
@@ -169,8 +194,13 @@ func buildResourceDef(name string, spec codegen.ControlResourceSpec, resolver *c
 			desc = propertyDescription(name, fieldName, resolver)
 			defVal, hasDef = propertyDefaultLiteral(name, fieldName, resolver)
 		}
+		if input.Type == "" {
+			prop := lookupResourceProperty(name, fieldName, resolver)
+			constraints = append(constraints, codegen.ConstraintLines(strings.Split(tag, ",")[0], prop, resolver)...)
+		}
 		inputs = append(inputs, resourceField{
 			Name:       fieldName,
+			Property:   codegen.LowerCamel(fieldName),
 			Type:       fieldType,
 			Tag:        tag,
 			Desc:       desc,
@@ -218,6 +248,7 @@ func buildResourceDef(name string, spec codegen.ControlResourceSpec, resolver *c
 		}
 		outputs = append(outputs, resourceField{
 			Name:       fieldName,
+			Property:   codegen.LowerCamel(fieldName),
 			Type:       fieldType,
 			Tag:        tag,
 			Desc:       desc,
@@ -230,6 +261,7 @@ func buildResourceDef(name string, spec codegen.ControlResourceSpec, resolver *c
 
 	argsAnnotate := buildAnnotateLines(inputs)
 	stateAnnotate := buildAnnotateLines(outputs)
+	resultAnnotate := append(buildDescribeLines(inputs), buildDescribeLines(outputs)...)
 	resourceDesc := schemaDescriptionString(name, resolver)
 
 	getFn := fmt.Sprintf("Get%sWithResponse", name)
@@ -252,6 +284,15 @@ func buildResourceDef(name string, spec codegen.ControlResourceSpec, resolver *c
 		}
 	}
 
+	replacePaths := []string{"tenant"}
+	if !spec.WithoutWorkspace {
+		replacePaths = append(replacePaths, "workspace")
+	}
+	for _, extraPath := range spec.ExtraPaths {
+		replacePaths = append(replacePaths, codegen.CamelCase(extraPath))
+	}
+	replacePaths = append(replacePaths, spec.ReplaceOnChanges...)
+
 	s := strings.Split(spec.APIPackage, "/")
 	return resourceDef{
 		Name:                 name,
@@ -266,11 +307,17 @@ func buildResourceDef(name string, spec codegen.ControlResourceSpec, resolver *c
 		ResourceDesc:         resourceDesc,
 		ArgsAnnotateLines:    argsAnnotate,
 		StateAnnotateLines:   stateAnnotate,
+		ResultAnnotateLines:  resultAnnotate,
 		GetFn:                   getFn,
 		CreateFn:                createFn,
 		UpdateFn:                updateFn,
 		DeleteFn:                deleteFn,
 		ProviderPrefixOverwrite: spec.ProviderPrefixOverwrite,
+		UpdateParams:            strings.TrimSuffix(updateFn, "WithResponse") + "Params",
+		DeleteParams:            strings.TrimSuffix(deleteFn, "WithResponse") + "Params",
+		ReplacePaths:            replacePaths,
+		Constraints:             constraints,
+		LookupFunction:          spec.LookupFunction,
 	}
 }
 
@@ -408,6 +455,18 @@ func buildAnnotateLines(fields []resourceField) []string {
 	return lines
 }
 
+// buildDescribeLines describes the fields like buildAnnotateLines, without
+// the defaults that only apply to inputs.
+func buildDescribeLines(fields []resourceField) []string {
+	lines := make([]string, 0, len(fields))
+	for _, field := range fields {
+		if field.Annotate && field.Desc != "" {
+			lines = append(lines, fmt.Sprintf("a.Describe(&dto.%s, %q)", field.Name, field.Desc))
+		}
+	}
+	return lines
+}
+
 func schemaDescriptionString(name string, resolver *codegen.SchemaResolver) string {
 	if resolver == nil {
 		return ""
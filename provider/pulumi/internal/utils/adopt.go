@@ -0,0 +1,87 @@
+package utils
+
+import (
+	"context"
+
+	p "github.com/pulumi/pulumi-go-provider"
+)
+
+// OwnerAnnotation holds the URN of the Pulumi resource that created a
+// SecAPI resource. It is stamped on create so that a create interrupted
+// after the request was sent can take the resource over when retried.
+const OwnerAnnotation = "pulumi.cape-project.eu/urn"
+
+type urnKey struct{}
+
+// WithURN passes the URN of the resource being created on to Create, where it
+// is available through URN.
+func WithURN(provider p.Provider) p.Provider {
+	create := provider.Create
+	if create == nil {
+		return provider
+	}
+	provider.Create = func(ctx context.Context, req p.CreateRequest) (p.CreateResponse, error) {
+		return create(context.WithValue(ctx, urnKey{}, string(req.Urn)), req)
+	}
+	return provider
+}
+
+// URN returns the URN of the resource being created, or an empty string if
+// it is not known.
+func URN(ctx context.Context) string {
+	urn, _ := ctx.Value(urnKey{}).(string)
+	return urn
+}
+
+// AdoptExisting reports whether create may take over an existing resource
+// with the same name. The resource option overwrites the provider default.
+func AdoptExisting(resource, provider *bool) bool {
+	if resource != nil {
+		return *resource
+	}
+	return provider != nil && *provider
+}
+
+// WithOwner returns a copy of the annotations with the owner annotation set
+// to urn. Without a urn, the annotations are returned as they are.
+func WithOwner[A ~map[string]string](annotations *A, urn string) *A {
+	if urn == "" {
+		return annotations
+	}
+	owned := A{}
+	if annotations != nil {
+		for key, value := range *annotations {
+			owned[key] = value
+		}
+	}
+	owned[OwnerAnnotation] = urn
+	return &owned
+}
+
+// WithoutOwner returns the annotations without the owner annotation, so that
+// it never shows up in the state. Nil is returned if nothing is left.
+func WithoutOwner[A ~map[string]string](annotations *A) *A {
+	if annotations == nil {
+		return nil
+	}
+	if _, ok := (*annotations)[OwnerAnnotation]; !ok {
+		return annotations
+	}
+	if len(*annotations) == 1 {
+		return nil
+	}
+	rest := A{}
+	for key, value := range *annotations {
+		if key != OwnerAnnotation {
+			rest[key] = value
+		}
+	}
+	return &rest
+}
+
+// IsOwnedBy reports whether the annotations of an existing resource name urn
+// as their owner, as they do when a create of the same resource was
+// interrupted.
+func IsOwnedBy[A ~map[string]string](annotations *A, urn string) bool {
+	return urn != "" && annotations != nil && (*annotations)[OwnerAnnotation] == urn
+}
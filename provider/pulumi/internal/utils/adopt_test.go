@@ -0,0 +1,93 @@
+package utils
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	p "github.com/pulumi/pulumi-go-provider"
+)
+
+type annotations map[string]string
+
+const testURN = "urn:pulumi:dev::project::cape:storage:BlockStorage::volume"
+
+func TestAdoptExisting(t *testing.T) {
+	yes, no := true, false
+	tests := []struct {
+		name               string
+		resource, provider *bool
+		want               bool
+	}{
+		{name: "unset", want: false},
+		{name: "provider default", provider: &yes, want: true},
+		{name: "resource overwrites provider", resource: &no, provider: &yes, want: false},
+		{name: "resource only", resource: &yes, want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := AdoptExisting(tt.resource, tt.provider); got != tt.want {
+				t.Errorf("AdoptExisting() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithURN(t *testing.T) {
+	var got string
+	provider := WithURN(p.Provider{Create: func(ctx context.Context, req p.CreateRequest) (p.CreateResponse, error) {
+		got = URN(ctx)
+		return p.CreateResponse{}, nil
+	}})
+
+	if _, err := provider.Create(context.Background(), p.CreateRequest{Urn: testURN}); err != nil {
+		t.Fatal(err)
+	}
+	if got != testURN {
+		t.Errorf("URN() = %q, want %q", got, testURN)
+	}
+	if urn := URN(context.Background()); urn != "" {
+		t.Errorf("URN() = %q outside of Create, want none", urn)
+	}
+}
+
+func TestOwnerAnnotation(t *testing.T) {
+	inputs := &annotations{"team": "storage"}
+
+	owned := WithOwner(inputs, testURN)
+	want := &annotations{"team": "storage", OwnerAnnotation: testURN}
+	if !reflect.DeepEqual(owned, want) {
+		t.Errorf("WithOwner() = %v, want %v", *owned, *want)
+	}
+	if _, ok := (*inputs)[OwnerAnnotation]; ok {
+		t.Error("WithOwner() modified the inputs")
+	}
+	if got := WithOwner(inputs, ""); got != inputs {
+		t.Errorf("WithOwner() without URN = %v, want the inputs", got)
+	}
+	if got := WithOwner[annotations](nil, testURN); !reflect.DeepEqual(got, &annotations{OwnerAnnotation: testURN}) {
+		t.Errorf("WithOwner(nil) = %v", got)
+	}
+
+	if !IsOwnedBy(owned, testURN) {
+		t.Error("IsOwnedBy() = false for the owner")
+	}
+	for _, urn := range []string{"", "urn:pulumi:prod::project::cape:storage:BlockStorage::volume"} {
+		if IsOwnedBy(owned, urn) {
+			t.Errorf("IsOwnedBy(%q) = true", urn)
+		}
+	}
+	if IsOwnedBy(inputs, testURN) || IsOwnedBy[annotations](nil, testURN) {
+		t.Error("IsOwnedBy() = true without owner annotation")
+	}
+
+	if got := WithoutOwner(owned); !reflect.DeepEqual(got, inputs) {
+		t.Errorf("WithoutOwner() = %v, want %v", *got, *inputs)
+	}
+	if got := WithoutOwner(&annotations{OwnerAnnotation: testURN}); got != nil {
+		t.Errorf("WithoutOwner() = %v, want nil", *got)
+	}
+	if got := WithoutOwner(inputs); got != inputs {
+		t.Errorf("WithoutOwner() = %v, want the inputs", got)
+	}
+}
@@ -0,0 +1,154 @@
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"cape-project.eu/provider/pulumi/secapi/models"
+)
+
+// ErrorKind classifies SecAPI errors for callers that need to react on them.
+type ErrorKind string
+
+const (
+	ErrorKindValidation ErrorKind = "validation"
+	ErrorKindAuth       ErrorKind = "auth"
+	ErrorKindNotFound   ErrorKind = "not-found"
+	ErrorKindConflict   ErrorKind = "conflict"
+	ErrorKindQuota      ErrorKind = "quota"
+	ErrorKindServer     ErrorKind = "server"
+	ErrorKindUnknown    ErrorKind = "unknown"
+)
+
+// FieldViolation points to an invalid part of a request.
+type FieldViolation struct {
+	Field   string
+	Message string
+}
+
+// APIError is a failed SecAPI call, decoded from the SecAPI error document
+// (RFC 9457 problem details) in the response body where possible.
+type APIError struct {
+	Operation  string
+	StatusCode int
+	// Code identifies the problem type, e.g. "quota-exceeded" for the type
+	// https://secapi.eu/errors/quota-exceeded.
+	Code       string
+	Type       string
+	Title      string
+	Detail     string
+	Violations []FieldViolation
+	RequestID  string
+	// Body holds the raw response if it is not an error document.
+	Body string
+}
+
+// NewAPIError decodes the error response of an operation, e.g.
+// "create BlockStorage my-volume".
+func NewAPIError(operation string, statusCode int, res *http.Response, body []byte) *APIError {
+	apiErr := &APIError{Operation: operation, StatusCode: statusCode}
+	if res != nil {
+		apiErr.RequestID = res.Header.Get("X-Request-Id")
+	}
+
+	var problem models.Error
+	if err := json.Unmarshal(body, &problem); err != nil || problem.Type == "" && problem.Title == "" && deref(problem.Detail) == "" {
+		apiErr.Body = strings.TrimSpace(string(body))
+		return apiErr
+	}
+
+	apiErr.Type = problem.Type
+	apiErr.Code = problemCode(problem.Type)
+	apiErr.Title = problem.Title
+	apiErr.Detail = deref(problem.Detail)
+	if problem.Sources != nil {
+		for _, source := range *problem.Sources {
+			field := deref(source.Pointer)
+			if field == "" {
+				field = deref(source.Parameter)
+			}
+			apiErr.Violations = append(apiErr.Violations, FieldViolation{Field: field, Message: apiErr.Detail})
+		}
+	}
+	return apiErr
+}
+
+// problemCode returns the last path segment of a problem type URI.
+func problemCode(problemType string) string {
+	code := strings.TrimRight(problemType, "/")
+	if i := strings.LastIndexAny(code, "/#:"); i >= 0 {
+		code = code[i+1:]
+	}
+	return code
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+// Kind classifies the error by its status code and problem type.
+func (e *APIError) Kind() ErrorKind {
+	switch {
+	case strings.Contains(strings.ToLower(e.Code), "quota"):
+		return ErrorKindQuota
+	case e.StatusCode == http.StatusBadRequest, e.StatusCode == http.StatusUnprocessableEntity:
+		return ErrorKindValidation
+	case e.StatusCode == http.StatusUnauthorized, e.StatusCode == http.StatusForbidden:
+		return ErrorKindAuth
+	case e.StatusCode == http.StatusNotFound:
+		return ErrorKindNotFound
+	case IsConflict(e.StatusCode):
+		return ErrorKindConflict
+	case e.StatusCode == http.StatusTooManyRequests:
+		return ErrorKindQuota
+	case e.StatusCode >= 500:
+		return ErrorKindServer
+	}
+	return ErrorKindUnknown
+}
+
+func (e *APIError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s failed with %d %s (%s error)", e.Operation, e.StatusCode, http.StatusText(e.StatusCode), e.Kind())
+	if e.Code != "" {
+		fmt.Fprintf(&b, " [%s]", e.Code)
+	}
+
+	message := e.Title
+	if e.Detail != "" {
+		if message != "" {
+			message += ": "
+		}
+		message += e.Detail
+	}
+	if message == "" {
+		message = e.Body
+	}
+	if message != "" {
+		b.WriteString(": ")
+		b.WriteString(message)
+	}
+	if e.RequestID != "" {
+		fmt.Fprintf(&b, " [request ID %s]", e.RequestID)
+	}
+	for _, violation := range e.Violations {
+		fmt.Fprintf(&b, "\n  - %s: %s", violation.Field, violation.Message)
+	}
+	return b.String()
+}
+
+// ErrorKindOf returns the kind of the APIError wrapped by err, or
+// ErrorKindUnknown if there is none.
+func ErrorKindOf(err error) ErrorKind {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.Kind()
+	}
+	return ErrorKindUnknown
+}
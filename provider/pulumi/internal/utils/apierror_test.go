@@ -0,0 +1,85 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNewAPIError(t *testing.T) {
+	res := &http.Response{Header: http.Header{"X-Request-Id": {"req-1"}}}
+	body := `{
+		"type": "https://secapi.eu/errors/quota-exceeded",
+		"title": "Quota exceeded",
+		"status": 422,
+		"detail": "no more than 10 volumes",
+		"sources": [{"pointer": "/spec/sizeGB"}, {"parameter": "name"}]
+	}`
+
+	apiErr := NewAPIError("create BlockStorage volume", http.StatusUnprocessableEntity, res, []byte(body))
+	want := &APIError{
+		Operation:  "create BlockStorage volume",
+		StatusCode: http.StatusUnprocessableEntity,
+		Code:       "quota-exceeded",
+		Type:       "https://secapi.eu/errors/quota-exceeded",
+		Title:      "Quota exceeded",
+		Detail:     "no more than 10 volumes",
+		Violations: []FieldViolation{
+			{Field: "/spec/sizeGB", Message: "no more than 10 volumes"},
+			{Field: "name", Message: "no more than 10 volumes"},
+		},
+		RequestID: "req-1",
+	}
+	if !reflect.DeepEqual(apiErr, want) {
+		t.Fatalf("NewAPIError() = %+v, want %+v", apiErr, want)
+	}
+	if apiErr.Kind() != ErrorKindQuota {
+		t.Errorf("Kind() = %s, want %s", apiErr.Kind(), ErrorKindQuota)
+	}
+
+	message := apiErr.Error()
+	for _, part := range []string{"422", "[quota-exceeded]", "Quota exceeded: no more than 10 volumes", "req-1", "/spec/sizeGB"} {
+		if !strings.Contains(message, part) {
+			t.Errorf("Error() = %q, want it to contain %q", message, part)
+		}
+	}
+}
+
+func TestNewAPIErrorRawBody(t *testing.T) {
+	for _, body := range []string{"upstream timed out\n", `{"error": "not found"}`, ""} {
+		apiErr := NewAPIError("get Workspace ws", http.StatusBadGateway, nil, []byte(body))
+		if apiErr.Body != strings.TrimSpace(body) || apiErr.Title != "" || apiErr.Code != "" {
+			t.Errorf("NewAPIError(%q) = %+v, want the raw body", body, apiErr)
+		}
+	}
+}
+
+func TestErrorKind(t *testing.T) {
+	tests := []struct {
+		status int
+		want   ErrorKind
+	}{
+		{http.StatusBadRequest, ErrorKindValidation},
+		{http.StatusUnprocessableEntity, ErrorKindValidation},
+		{http.StatusUnauthorized, ErrorKindAuth},
+		{http.StatusForbidden, ErrorKindAuth},
+		{http.StatusNotFound, ErrorKindNotFound},
+		{http.StatusConflict, ErrorKindConflict},
+		{http.StatusPreconditionFailed, ErrorKindConflict},
+		{http.StatusTooManyRequests, ErrorKindQuota},
+		{http.StatusServiceUnavailable, ErrorKindServer},
+		{http.StatusTeapot, ErrorKindUnknown},
+	}
+	for _, tt := range tests {
+		err := fmt.Errorf("wrapped: %w", &APIError{StatusCode: tt.status})
+		if got := ErrorKindOf(err); got != tt.want {
+			t.Errorf("ErrorKindOf(%d) = %s, want %s", tt.status, got, tt.want)
+		}
+	}
+	if got := ErrorKindOf(errors.New("dial failed")); got != ErrorKindUnknown {
+		t.Errorf("ErrorKindOf() = %s for a non-API error", got)
+	}
+}
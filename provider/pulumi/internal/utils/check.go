@@ -0,0 +1,179 @@
+package utils
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	p "github.com/pulumi/pulumi-go-provider"
+	"github.com/pulumi/pulumi/sdk/v3/go/property"
+)
+
+// Constraint validates the input value at a property path. Paths use the
+// Pulumi property syntax, with [*] matching every element of an array or map,
+// e.g. spec.rules[*].port or labels[*].
+type Constraint struct {
+	Path string
+
+	// required reports whether the value must be present at all; the check
+	// function is only called for values that are present and known.
+	required bool
+	check    func(value property.Value) string
+}
+
+// Required fails if the property is missing while its parent is set.
+func Required(path string) Constraint {
+	return Constraint{Path: path, required: true}
+}
+
+// Minimum fails if a number is lower than min.
+func Minimum(path string, min float64) Constraint {
+	return Constraint{Path: path, check: func(value property.Value) string {
+		if value.IsNumber() && value.AsNumber() < min {
+			return fmt.Sprintf("must be greater than or equal to %v", min)
+		}
+		return ""
+	}}
+}
+
+// Maximum fails if a number is greater than max.
+func Maximum(path string, max float64) Constraint {
+	return Constraint{Path: path, check: func(value property.Value) string {
+		if value.IsNumber() && value.AsNumber() > max {
+			return fmt.Sprintf("must be less than or equal to %v", max)
+		}
+		return ""
+	}}
+}
+
+// MinLength fails if a string has fewer than min characters.
+func MinLength(path string, min int) Constraint {
+	return Constraint{Path: path, check: func(value property.Value) string {
+		if value.IsString() && utf8.RuneCountInString(value.AsString()) < min {
+			return fmt.Sprintf("must be at least %d characters long", min)
+		}
+		return ""
+	}}
+}
+
+// MaxLength fails if a string has more than max characters.
+func MaxLength(path string, max int) Constraint {
+	return Constraint{Path: path, check: func(value property.Value) string {
+		if value.IsString() && utf8.RuneCountInString(value.AsString()) > max {
+			return fmt.Sprintf("must be at most %d characters long", max)
+		}
+		return ""
+	}}
+}
+
+// Pattern fails if a string does not match the regular expression expr,
+// which must be valid RE2 syntax.
+func Pattern(path, expr string) Constraint {
+	return Constraint{Path: path, check: func(value property.Value) string {
+		if value.IsString() && !compilePattern(expr).MatchString(value.AsString()) {
+			return fmt.Sprintf("must match the pattern %q", expr)
+		}
+		return ""
+	}}
+}
+
+// Enum fails if a value is not one of the allowed values.
+func Enum(path string, allowed ...string) Constraint {
+	return Constraint{Path: path, check: func(value property.Value) string {
+		var actual string
+		switch {
+		case value.IsString():
+			actual = value.AsString()
+		case value.IsNumber():
+			actual = fmt.Sprint(value.AsNumber())
+		case value.IsBool():
+			actual = fmt.Sprint(value.AsBool())
+		default:
+			return ""
+		}
+		for _, a := range allowed {
+			if a == actual {
+				return ""
+			}
+		}
+		return fmt.Sprintf("must be one of %s", strings.Join(allowed, ", "))
+	}}
+}
+
+// Validate checks the inputs against the constraints. Values that are not
+// known yet (e.g. outputs of other resources during a preview) are skipped.
+func Validate(inputs property.Map, constraints []Constraint) []p.CheckFailure {
+	var failures []p.CheckFailure
+	root := property.New(inputs)
+	for _, constraint := range constraints {
+		segments := strings.Split(strings.ReplaceAll(constraint.Path, "[*]", ".[*]"), ".")
+		visit(root, "", segments, func(path string, value property.Value, ok bool) {
+			if !ok || value.IsNull() {
+				if constraint.required {
+					failures = append(failures, p.CheckFailure{Property: path, Reason: "is required"})
+				}
+				return
+			}
+			if constraint.check == nil || value.IsComputed() {
+				return
+			}
+			if reason := constraint.check(value); reason != "" {
+				failures = append(failures, p.CheckFailure{Property: path, Reason: reason})
+			}
+		})
+	}
+	return failures
+}
+
+// visit calls fn for every value matching the remaining path segments. The
+// walk stops silently at missing or unknown parents.
+func visit(value property.Value, path string, segments []string, fn func(path string, value property.Value, ok bool)) {
+	if len(segments) == 0 {
+		fn(path, value, true)
+		return
+	}
+	if value.IsComputed() {
+		return
+	}
+
+	segment := segments[0]
+	switch {
+	case segment == "[*]" && value.IsArray():
+		for i, item := range value.AsArray().AsSlice() {
+			visit(item, fmt.Sprintf("%s[%d]", path, i), segments[1:], fn)
+		}
+	case segment == "[*]" && value.IsMap():
+		items := value.AsMap().AsMap()
+		keys := make([]string, 0, len(items))
+		for key := range items {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			visit(items[key], fmt.Sprintf("%s[%q]", path, key), segments[1:], fn)
+		}
+	case segment != "[*]" && value.IsMap():
+		child, ok := value.AsMap().GetOk(segment)
+		if len(segments) == 1 {
+			fn(joinPath(path, segment), child, ok)
+			return
+		}
+		if ok {
+			visit(child, joinPath(path, segment), segments[1:], fn)
+		}
+	}
+}
+
+var patterns sync.Map
+
+func compilePattern(expr string) *regexp.Regexp {
+	if re, ok := patterns.Load(expr); ok {
+		return re.(*regexp.Regexp)
+	}
+	re := regexp.MustCompile(expr)
+	patterns.Store(expr, re)
+	return re
+}
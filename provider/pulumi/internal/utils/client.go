@@ -0,0 +1,53 @@
+package utils
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"cape-project.eu/provider/pulumi/config"
+)
+
+// ProviderURL joins the configured base URL with the provider prefix of an
+// API, taking care of duplicated or missing slashes.
+func ProviderURL(baseURL, prefix string) string {
+	if prefix == "" {
+		return baseURL
+	}
+	switch true {
+	case strings.HasSuffix(baseURL, "/") && strings.HasPrefix(prefix, "/"):
+		return baseURL + prefix[1:]
+	case !strings.HasSuffix(baseURL, "/") && !strings.HasPrefix(prefix, "/"):
+		return baseURL + "/" + prefix
+	default:
+		return baseURL + prefix
+	}
+}
+
+// ResolveAuthToken returns the bearer token used for calls of the given
+// resource or function. Overrides in AuthTokens are looked up by
+// "<package>:<name>" first, then by "<package>", before falling back to
+// AuthToken.
+func ResolveAuthToken(cfg config.Config, pkg, name string) string {
+	if token, ok := cfg.AuthTokens[pkg+":"+name]; ok {
+		return token
+	}
+	if token, ok := cfg.AuthTokens[pkg]; ok {
+		return token
+	}
+	if cfg.AuthToken != nil {
+		return *cfg.AuthToken
+	}
+	return ""
+}
+
+// BearerTokenEditor returns a request editor that attaches the token as
+// bearer credential. An empty token leaves the request untouched.
+func BearerTokenEditor(token string) func(ctx context.Context, req *http.Request) error {
+	return func(_ context.Context, req *http.Request) error {
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,98 @@
+package utils
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"cape-project.eu/provider/pulumi/config"
+)
+
+func TestProviderURL(t *testing.T) {
+	tests := []struct {
+		baseURL, prefix, want string
+	}{
+		{baseURL: "https://api.example.com", prefix: "providers/seca.storage", want: "https://api.example.com/providers/seca.storage"},
+		{baseURL: "https://api.example.com/", prefix: "/providers/seca.storage", want: "https://api.example.com/providers/seca.storage"},
+		{baseURL: "https://api.example.com/", prefix: "providers/seca.storage", want: "https://api.example.com/providers/seca.storage"},
+		{baseURL: "https://api.example.com", prefix: "/providers/seca.storage", want: "https://api.example.com/providers/seca.storage"},
+		{baseURL: "https://api.example.com/", prefix: "", want: "https://api.example.com/"},
+	}
+	for _, tt := range tests {
+		if got := ProviderURL(tt.baseURL, tt.prefix); got != tt.want {
+			t.Errorf("ProviderURL(%q, %q) = %q, want %q", tt.baseURL, tt.prefix, got, tt.want)
+		}
+	}
+}
+
+func TestResolveAuthToken(t *testing.T) {
+	token := func(v string) *string { return &v }
+
+	tests := []struct {
+		name string
+		cfg  config.Config
+		want string
+	}{
+		{
+			name: "resource override",
+			cfg:  config.Config{AuthToken: token("default"), AuthTokens: map[string]string{"storage": "package", "storage:BlockStorage": "resource"}},
+			want: "resource",
+		},
+		{
+			name: "package override",
+			cfg:  config.Config{AuthToken: token("default"), AuthTokens: map[string]string{"storage": "package", "storage:Image": "other resource"}},
+			want: "package",
+		},
+		{
+			name: "other package",
+			cfg:  config.Config{AuthToken: token("default"), AuthTokens: map[string]string{"compute": "package", "compute:BlockStorage": "resource"}},
+			want: "default",
+		},
+		{
+			name: "empty override",
+			cfg:  config.Config{AuthToken: token("default"), AuthTokens: map[string]string{"storage:BlockStorage": ""}},
+			want: "",
+		},
+		{
+			name: "default",
+			cfg:  config.Config{AuthToken: token("default")},
+			want: "default",
+		},
+		{
+			name: "no token",
+			cfg:  config.Config{},
+			want: "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ResolveAuthToken(tt.cfg, "storage", "BlockStorage"); got != tt.want {
+				t.Errorf("ResolveAuthToken() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBearerTokenEditor(t *testing.T) {
+	req, err := http.NewRequest(http.MethodGet, "https://api.example.com", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := BearerTokenEditor("secret")(context.Background(), req); err != nil {
+		t.Fatal(err)
+	}
+	if got := req.Header.Get("Authorization"); got != "Bearer secret" {
+		t.Errorf("Authorization = %q, want %q", got, "Bearer secret")
+	}
+
+	req, err = http.NewRequest(http.MethodGet, "https://api.example.com", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := BearerTokenEditor("")(context.Background(), req); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := req.Header["Authorization"]; ok {
+		t.Errorf("Authorization = %q without a token, want none", req.Header.Get("Authorization"))
+	}
+}
@@ -0,0 +1,243 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+
+	p "github.com/pulumi/pulumi-go-provider"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/util/mapper"
+	"github.com/pulumi/pulumi/sdk/v3/go/property"
+)
+
+// Diff collects property-level differences between the old state and the new
+// inputs of a resource. Property paths follow the Pulumi detailed diff
+// syntax, e.g. spec.skuRef.resource or labels["env"].
+type Diff struct {
+	replacePaths []string
+	detailed     map[string]p.PropertyDiff
+}
+
+// NewDiff creates a Diff that forces a replacement whenever one of the given
+// property paths, or anything below it, changes.
+func NewDiff(replacePaths ...string) *Diff {
+	return &Diff{
+		replacePaths: replacePaths,
+		detailed:     map[string]p.PropertyDiff{},
+	}
+}
+
+// Compare records the differences between an old and a new value of the
+// property at path. Structs are compared field by field using their pulumi
+// tags, maps key by key and slices element by element.
+func (d *Diff) Compare(path string, oldValue, newValue any) {
+	d.compare(path, reflect.ValueOf(oldValue), reflect.ValueOf(newValue))
+}
+
+// Response builds the Pulumi diff response. Replacements delete the old
+// resource first, as SecAPI names are fixed and a second resource with the
+// same name cannot be created next to it.
+func (d *Diff) Response() p.DiffResponse {
+	replace := false
+	for _, diff := range d.detailed {
+		switch diff.Kind {
+		case p.AddReplace, p.UpdateReplace, p.DeleteReplace:
+			replace = true
+		}
+	}
+	return p.DiffResponse{
+		DeleteBeforeReplace: replace,
+		HasChanges:          len(d.detailed) > 0,
+		DetailedDiff:        d.detailed,
+	}
+}
+
+func (d *Diff) compare(path string, oldValue, newValue reflect.Value) {
+	oldValue = indirect(oldValue)
+	newValue = indirect(newValue)
+
+	oldEmpty, newEmpty := isEmpty(oldValue), isEmpty(newValue)
+	switch {
+	case oldEmpty && newEmpty:
+		return
+	case oldEmpty:
+		d.record(path, p.Add)
+		return
+	case newEmpty:
+		d.record(path, p.Delete)
+		return
+	}
+
+	if oldValue.Kind() != newValue.Kind() {
+		d.record(path, p.Update)
+		return
+	}
+	if oldValue.Kind() == reflect.Struct && oldValue.Type() != newValue.Type() {
+		if !reflect.DeepEqual(oldValue.Interface(), newValue.Interface()) {
+			d.record(path, p.Update)
+		}
+		return
+	}
+
+	switch oldValue.Kind() {
+	case reflect.Struct:
+		for i := 0; i < oldValue.NumField(); i++ {
+			field := oldValue.Type().Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			if field.Anonymous {
+				d.compare(path, oldValue.Field(i), newValue.Field(i))
+				continue
+			}
+			d.compare(joinPath(path, propertyName(field)), oldValue.Field(i), newValue.Field(i))
+		}
+	case reflect.Map:
+		keys := map[string]reflect.Value{}
+		for _, key := range oldValue.MapKeys() {
+			keys[fmt.Sprint(key.Interface())] = key
+		}
+		for _, key := range newValue.MapKeys() {
+			keys[fmt.Sprint(key.Interface())] = key
+		}
+		for name, key := range keys {
+			d.compare(fmt.Sprintf("%s[%q]", path, name), oldValue.MapIndex(key), newValue.MapIndex(key))
+		}
+	case reflect.Slice, reflect.Array:
+		length := max(oldValue.Len(), newValue.Len())
+		for i := 0; i < length; i++ {
+			var oldItem, newItem reflect.Value
+			if i < oldValue.Len() {
+				oldItem = oldValue.Index(i)
+			}
+			if i < newValue.Len() {
+				newItem = newValue.Index(i)
+			}
+			d.compare(fmt.Sprintf("%s[%d]", path, i), oldItem, newItem)
+		}
+	default:
+		if !equalScalar(oldValue, newValue) {
+			d.record(path, p.Update)
+		}
+	}
+}
+
+func (d *Diff) record(path string, kind p.DiffKind) {
+	if d.forcesReplace(path) {
+		switch kind {
+		case p.Add:
+			kind = p.AddReplace
+		case p.Delete:
+			kind = p.DeleteReplace
+		case p.Update:
+			kind = p.UpdateReplace
+		}
+	}
+	d.detailed[path] = p.PropertyDiff{Kind: kind, InputDiff: true}
+}
+
+func (d *Diff) forcesReplace(path string) bool {
+	for _, replacePath := range d.replacePaths {
+		if path == replacePath || isSubPath(path, replacePath) || isSubPath(replacePath, path) {
+			return true
+		}
+	}
+	return false
+}
+
+func isSubPath(path, parent string) bool {
+	return strings.HasPrefix(path, parent+".") || strings.HasPrefix(path, parent+"[")
+}
+
+func joinPath(parent, name string) string {
+	if parent == "" {
+		return name
+	}
+	return parent + "." + name
+}
+
+func propertyName(field reflect.StructField) string {
+	tag := field.Tag.Get("pulumi")
+	if name, _, _ := strings.Cut(tag, ","); name != "" {
+		return name
+	}
+	runes := []rune(field.Name)
+	runes[0] = []rune(strings.ToLower(string(runes[0])))[0]
+	return string(runes)
+}
+
+func indirect(value reflect.Value) reflect.Value {
+	for value.IsValid() && (value.Kind() == reflect.Pointer || value.Kind() == reflect.Interface) {
+		if value.IsNil() {
+			return reflect.Value{}
+		}
+		value = value.Elem()
+	}
+	return value
+}
+
+func isEmpty(value reflect.Value) bool {
+	if !value.IsValid() {
+		return true
+	}
+	switch value.Kind() {
+	case reflect.Map, reflect.Slice:
+		return value.Len() == 0
+	}
+	return false
+}
+
+func equalScalar(oldValue, newValue reflect.Value) bool {
+	switch oldValue.Kind() {
+	case reflect.String:
+		return oldValue.String() == newValue.String()
+	case reflect.Bool:
+		return oldValue.Bool() == newValue.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return oldValue.Int() == newValue.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return oldValue.Uint() == newValue.Uint()
+	case reflect.Float32, reflect.Float64:
+		return oldValue.Float() == newValue.Float()
+	}
+	return reflect.DeepEqual(oldValue.Interface(), newValue.Interface())
+}
+
+type oldInputsKey struct{}
+
+// WithOldInputs passes the inputs of the last deployment on to Diff, where
+// they are available through OldInputs. The typed diff requests of infer only
+// carry the state, which also holds the values filled in by the server.
+func WithOldInputs(provider p.Provider) p.Provider {
+	diff := provider.Diff
+	if diff == nil {
+		return provider
+	}
+	provider.Diff = func(ctx context.Context, req p.DiffRequest) (p.DiffResponse, error) {
+		return diff(context.WithValue(ctx, oldInputsKey{}, req.OldInputs), req)
+	}
+	return provider
+}
+
+// OldInputs decodes the inputs of the last deployment of the resource being
+// diffed into the arguments of the resource.
+func OldInputs[A any](ctx context.Context) (A, error) {
+	var args A
+	inputs, _ := ctx.Value(oldInputsKey{}).(property.Map)
+	mappable := resource.ToResourcePropertyMap(inputs).MapRepl(nil, unsecret)
+	if err := mapper.MapI(mappable, &args); err != nil {
+		return args, fmt.Errorf("decoding the old inputs: %w", err)
+	}
+	return args, nil
+}
+
+// unsecret replaces secrets by their values, which the mapper cannot decode
+// otherwise.
+func unsecret(value resource.PropertyValue) (any, bool) {
+	if !value.IsSecret() {
+		return nil, false
+	}
+	return value.SecretValue().Element.MapRepl(nil, unsecret), true
+}
@@ -0,0 +1,134 @@
+package utils
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	p "github.com/pulumi/pulumi-go-provider"
+	"github.com/pulumi/pulumi/sdk/v3/go/property"
+)
+
+type diffSpec struct {
+	Size    *int              `pulumi:"size,optional"`
+	Tier    *string           `pulumi:"tier,optional"`
+	Tags    []string          `pulumi:"tags,optional"`
+	Options map[string]string `pulumi:"options,optional"`
+}
+
+func TestDiffCompare(t *testing.T) {
+	oldSpec := diffSpec{Size: ptr(10), Tier: ptr("standard"), Tags: []string{"a", "b"}}
+	newSpec := diffSpec{Size: ptr(20), Tags: []string{"a"}, Options: map[string]string{"x": "y"}}
+
+	diff := NewDiff("spec.size")
+	diff.Compare("spec", oldSpec, newSpec)
+
+	want := map[string]p.PropertyDiff{
+		"spec.size":    {Kind: p.UpdateReplace, InputDiff: true},
+		"spec.tier":    {Kind: p.Delete, InputDiff: true},
+		"spec.tags[1]": {Kind: p.Delete, InputDiff: true},
+		"spec.options": {Kind: p.Add, InputDiff: true},
+	}
+	res := diff.Response()
+	if !reflect.DeepEqual(res.DetailedDiff, want) {
+		t.Errorf("DetailedDiff = %v, want %v", res.DetailedDiff, want)
+	}
+	if !res.HasChanges || !res.DeleteBeforeReplace {
+		t.Errorf("HasChanges = %v, DeleteBeforeReplace = %v, want both", res.HasChanges, res.DeleteBeforeReplace)
+	}
+}
+
+type diffArgs struct {
+	Labels *map[string]string `pulumi:"labels,optional"`
+	Spec   *diffSpec          `pulumi:"spec,optional"`
+}
+
+func TestDiffOldInputs(t *testing.T) {
+	// The inputs of the last deployment; the server may have filled in more
+	// values, which only show up in the state.
+	oldInputs := property.NewMap(map[string]property.Value{
+		"labels": property.New(map[string]property.Value{
+			"env":  property.New("prod"),
+			"team": property.New("core"),
+		}),
+		"spec": property.New(map[string]property.Value{
+			"size": property.New(10.0),
+			"tier": property.New("standard").WithSecret(true),
+		}),
+	})
+
+	tests := []struct {
+		name   string
+		inputs diffArgs
+		want   map[string]p.PropertyDiff
+	}{
+		{
+			name:   "unchanged",
+			inputs: diffArgs{Labels: &map[string]string{"env": "prod", "team": "core"}, Spec: &diffSpec{Size: ptr(10), Tier: ptr("standard")}},
+			want:   map[string]p.PropertyDiff{},
+		},
+		{
+			name:   "removed labels",
+			inputs: diffArgs{Spec: &diffSpec{Size: ptr(10), Tier: ptr("standard")}},
+			want: map[string]p.PropertyDiff{
+				"labels": {Kind: p.Delete, InputDiff: true},
+			},
+		},
+		{
+			name:   "removed label",
+			inputs: diffArgs{Labels: &map[string]string{"env": "prod"}, Spec: &diffSpec{Size: ptr(10), Tier: ptr("standard")}},
+			want: map[string]p.PropertyDiff{
+				`labels["team"]`: {Kind: p.Delete, InputDiff: true},
+			},
+		},
+		{
+			name:   "removed optional spec field",
+			inputs: diffArgs{Labels: &map[string]string{"env": "prod", "team": "core"}, Spec: &diffSpec{Size: ptr(10)}},
+			want: map[string]p.PropertyDiff{
+				"spec.tier": {Kind: p.Delete, InputDiff: true},
+			},
+		},
+		{
+			name:   "changed and added",
+			inputs: diffArgs{Labels: &map[string]string{"env": "prod", "team": "core"}, Spec: &diffSpec{Size: ptr(20), Tier: ptr("standard"), Tags: []string{"a"}}},
+			want: map[string]p.PropertyDiff{
+				"spec.size": {Kind: p.Update, InputDiff: true},
+				"spec.tags": {Kind: p.Add, InputDiff: true},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := WithOldInputs(p.Provider{
+				Diff: func(ctx context.Context, _ p.DiffRequest) (p.DiffResponse, error) {
+					old, err := OldInputs[diffArgs](ctx)
+					if err != nil {
+						return p.DiffResponse{}, err
+					}
+					diff := NewDiff()
+					diff.Compare("labels", old.Labels, tt.inputs.Labels)
+					diff.Compare("spec", old.Spec, tt.inputs.Spec)
+					return diff.Response(), nil
+				},
+			})
+			res, err := provider.Diff(context.Background(), p.DiffRequest{OldInputs: oldInputs})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(res.DetailedDiff, tt.want) {
+				t.Errorf("DetailedDiff = %v, want %v", res.DetailedDiff, tt.want)
+			}
+		})
+	}
+}
+
+func TestOldInputsWithoutDiff(t *testing.T) {
+	old, err := OldInputs[diffArgs](context.Background())
+	if err != nil || old.Labels != nil || old.Spec != nil {
+		t.Errorf("OldInputs() = %+v, %v, want no inputs", old, err)
+	}
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
@@ -0,0 +1,44 @@
+package utils
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// FormatID builds a resource ID from the path of a resource, e.g.
+// "<tenant>/<workspace>/<name>". Parts are escaped so that the ID stays
+// parseable whatever characters they contain.
+func FormatID(parts ...string) string {
+	escaped := make([]string, len(parts))
+	for i, part := range parts {
+		escaped[i] = url.PathEscape(part)
+	}
+	return strings.Join(escaped, "/")
+}
+
+// ParseID splits an ID built by FormatID into the given named parts.
+func ParseID(id string, names ...string) (map[string]string, error) {
+	segments := strings.Split(id, "/")
+	if len(segments) != len(names) {
+		return nil, fmt.Errorf("invalid resource ID %q, expected %s", id, idFormat(names))
+	}
+
+	parts := make(map[string]string, len(names))
+	for i, segment := range segments {
+		part, err := url.PathUnescape(segment)
+		if err != nil || part == "" {
+			return nil, fmt.Errorf("invalid resource ID %q, expected %s", id, idFormat(names))
+		}
+		parts[names[i]] = part
+	}
+	return parts, nil
+}
+
+func idFormat(names []string) string {
+	format := make([]string, len(names))
+	for i, name := range names {
+		format[i] = "<" + name + ">"
+	}
+	return strings.Join(format, "/")
+}
@@ -0,0 +1,45 @@
+package utils
+
+import (
+	"maps"
+	"strings"
+	"testing"
+)
+
+func TestFormatID(t *testing.T) {
+	tests := []struct {
+		parts []string
+		want  string
+	}{
+		{parts: []string{"tenant", "workspace", "volume"}, want: "tenant/workspace/volume"},
+		{parts: []string{"tenant", "a/b", "c d"}, want: "tenant/a%2Fb/c%20d"},
+		{parts: []string{"tenant", "100%"}, want: "tenant/100%25"},
+	}
+	for _, tt := range tests {
+		if got := FormatID(tt.parts...); got != tt.want {
+			t.Errorf("FormatID(%q) = %q, want %q", tt.parts, got, tt.want)
+		}
+	}
+}
+
+func TestParseID(t *testing.T) {
+	names := []string{"tenant", "workspace", "name"}
+
+	for _, parts := range [][]string{{"t", "ws", "volume"}, {"t", "a/b", "c d"}, {"t", "100%", "x~y"}} {
+		got, err := ParseID(FormatID(parts...), names...)
+		if err != nil {
+			t.Fatalf("ParseID(FormatID(%q)) = %v", parts, err)
+		}
+		want := map[string]string{"tenant": parts[0], "workspace": parts[1], "name": parts[2]}
+		if !maps.Equal(got, want) {
+			t.Errorf("ParseID(FormatID(%q)) = %v, want %v", parts, got, want)
+		}
+	}
+
+	for _, id := range []string{"t/ws", "t/ws/volume/extra", "t//volume", "t/ws/%zz", ""} {
+		_, err := ParseID(id, names...)
+		if err == nil || !strings.Contains(err.Error(), "<tenant>/<workspace>/<name>") {
+			t.Errorf("ParseID(%q) = %v, want an error naming the format", id, err)
+		}
+	}
+}
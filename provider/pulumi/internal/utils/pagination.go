@@ -0,0 +1,55 @@
+package utils
+
+import "fmt"
+
+// DefaultMaxListItems bounds how many items a getter function collects by
+// following skip tokens if maxItems is not given.
+const DefaultMaxListItems = 10000
+
+// Pager follows the skip tokens of a list operation until the list is
+// exhausted.
+type Pager struct {
+	operation string
+	follow    bool
+	maxItems  int
+	items     int
+	seen      map[string]bool
+}
+
+// NewPager returns a pager for a getter function. Skip tokens are followed
+// unless autoPaginate is false, up to maxItems items.
+func NewPager(operation string, autoPaginate *bool, maxItems *int) (*Pager, error) {
+	p := &Pager{
+		operation: operation,
+		follow:    autoPaginate == nil || *autoPaginate,
+		maxItems:  DefaultMaxListItems,
+		seen:      map[string]bool{},
+	}
+	if maxItems != nil {
+		if *maxItems <= 0 {
+			return nil, fmt.Errorf("invalid maxItems %d: must be positive", *maxItems)
+		}
+		p.maxItems = *maxItems
+	}
+	return p, nil
+}
+
+// NextPage records a page of count items ending with token. If there is a
+// page to follow, it sets skipToken to fetch it and reports true.
+func NextPage[T, P ~string](p *Pager, count int, token *T, skipToken **P) (bool, error) {
+	p.items += count
+	if !p.follow || token == nil || *token == "" {
+		return false, nil
+	}
+	if p.items >= p.maxItems {
+		return false, fmt.Errorf("%s: the list has more than %d items, raise maxItems or narrow it down with labels", p.operation, p.maxItems)
+	}
+	if p.seen[string(*token)] {
+		return false, fmt.Errorf("%s: the API returned skip token %q twice", p.operation, *token)
+	}
+	p.seen[string(*token)] = true
+
+	next := P(*token)
+	*skipToken = &next
+	return true, nil
+}
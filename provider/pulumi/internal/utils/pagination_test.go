@@ -0,0 +1,80 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+)
+
+type testSkipToken string
+
+func TestNewPager(t *testing.T) {
+	pager, err := NewPager("storage:ListBlockStorages", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !pager.follow || pager.maxItems != DefaultMaxListItems {
+		t.Errorf("NewPager() = %+v, want it to follow up to %d items", pager, DefaultMaxListItems)
+	}
+
+	for _, maxItems := range []int{0, -1} {
+		if _, err := NewPager("storage:ListBlockStorages", nil, &maxItems); err == nil {
+			t.Errorf("NewPager(maxItems %d) succeeded", maxItems)
+		}
+	}
+}
+
+func TestNextPage(t *testing.T) {
+	token := func(v string) *string { return &v }
+	follow := func(v bool) *bool { return &v }
+	maxItems := func(v int) *int { return &v }
+
+	tests := []struct {
+		name         string
+		autoPaginate *bool
+		maxItems     *int
+		pages        []*string
+		wantPages    int
+		wantErr      string
+	}{
+		{name: "single page", pages: []*string{nil}, wantPages: 1},
+		{name: "empty token", pages: []*string{token("")}, wantPages: 1},
+		{name: "follows tokens", pages: []*string{token("a"), token("b"), nil}, wantPages: 3},
+		{name: "no auto-pagination", autoPaginate: follow(false), pages: []*string{token("a"), token("b"), nil}, wantPages: 1},
+		{name: "repeated token", pages: []*string{token("a"), token("b"), token("a")}, wantErr: `skip token "a" twice`},
+		{name: "last page at max items", maxItems: maxItems(6), pages: []*string{token("a"), nil}, wantPages: 2},
+		{name: "more than max items", maxItems: maxItems(6), pages: []*string{token("a"), token("b"), nil}, wantErr: "more than 6 items"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pager, err := NewPager("storage:ListBlockStorages", tt.autoPaginate, tt.maxItems)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var skipToken *testSkipToken
+			pages := 0
+			for _, page := range tt.pages {
+				pages++
+				more, err := NextPage(pager, 3, page, &skipToken)
+				if err != nil {
+					if tt.wantErr == "" || !strings.Contains(err.Error(), tt.wantErr) {
+						t.Fatalf("NextPage() = %v, want %q", err, tt.wantErr)
+					}
+					return
+				}
+				if !more {
+					break
+				}
+				if skipToken == nil || string(*skipToken) != *page {
+					t.Fatalf("skipToken = %v, want %q", skipToken, *page)
+				}
+			}
+			if tt.wantErr != "" {
+				t.Fatalf("NextPage() succeeded, want %q", tt.wantErr)
+			}
+			if pages != tt.wantPages {
+				t.Errorf("pages = %d, want %d", pages, tt.wantPages)
+			}
+		})
+	}
+}
@@ -0,0 +1,37 @@
+package utils
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+)
+
+// SetPrecondition makes an update or delete fail unless the resource still
+// has the given version, by setting the precondition parameter of the
+// request. A zero version, as found in states written before versions were
+// tracked, sets no precondition.
+func SetPrecondition[V ~int | ~int32 | ~int64](param **V, version int64) {
+	if version > 0 {
+		value := V(version)
+		*param = &value
+	}
+}
+
+// IsConflict reports whether a status code signals a failed precondition.
+func IsConflict(statusCode int) bool {
+	return statusCode == http.StatusConflict || statusCode == http.StatusPreconditionFailed
+}
+
+// ConflictError describes a resource that was changed by someone else since
+// it was last read.
+func ConflictError(kind, name string, version int64, err error) error {
+	return fmt.Errorf("%s %s was modified outside of Pulumi since version %d was read, run `pulumi refresh` and retry: %w", kind, name, version, err)
+}
+
+// UpdateApplied reports whether an update whose precondition failed went
+// through after all. A retried update fails its precondition if the first
+// attempt was applied but its response was lost, which shows in the resource
+// having the sent spec at a version newer than the one the update expected.
+func UpdateApplied[S any](sent, current S, version, currentVersion int64) bool {
+	return version > 0 && currentVersion > version && reflect.DeepEqual(sent, current)
+}
@@ -0,0 +1,71 @@
+package utils
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestSetPrecondition(t *testing.T) {
+	var params struct {
+		IfUnmodifiedSince *int
+	}
+
+	SetPrecondition(&params.IfUnmodifiedSince, 0)
+	if params.IfUnmodifiedSince != nil {
+		t.Errorf("precondition = %d for version 0, want none", *params.IfUnmodifiedSince)
+	}
+	SetPrecondition(&params.IfUnmodifiedSince, 4)
+	if params.IfUnmodifiedSince == nil || *params.IfUnmodifiedSince != 4 {
+		t.Errorf("precondition = %v, want 4", params.IfUnmodifiedSince)
+	}
+}
+
+func TestConflictError(t *testing.T) {
+	for _, status := range []int{http.StatusConflict, http.StatusPreconditionFailed} {
+		if !IsConflict(status) {
+			t.Errorf("IsConflict(%d) = false", status)
+		}
+	}
+	if IsConflict(http.StatusNotFound) {
+		t.Error("IsConflict(404) = true")
+	}
+
+	cause := errors.New("version mismatch")
+	err := ConflictError("BlockStorage", "volume", 4, cause)
+	if !errors.Is(err, cause) {
+		t.Errorf("ConflictError() does not wrap %v", cause)
+	}
+	if !strings.Contains(err.Error(), "pulumi refresh") {
+		t.Errorf("ConflictError() = %q, want a hint to refresh", err)
+	}
+}
+
+func TestUpdateApplied(t *testing.T) {
+	type spec struct {
+		Size  int
+		Tiers []string
+	}
+	sent := spec{Size: 10, Tiers: []string{"RD500"}}
+
+	tests := []struct {
+		name           string
+		current        spec
+		version        int64
+		currentVersion int64
+		want           bool
+	}{
+		{name: "applied", current: spec{Size: 10, Tiers: []string{"RD500"}}, version: 4, currentVersion: 5, want: true},
+		{name: "changed by someone else", current: spec{Size: 20, Tiers: []string{"RD500"}}, version: 4, currentVersion: 5},
+		{name: "not changed since", current: sent, version: 4, currentVersion: 4},
+		{name: "no precondition", current: sent, currentVersion: 5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := UpdateApplied(sent, tt.current, tt.version, tt.currentVersion); got != tt.want {
+				t.Errorf("UpdateApplied() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
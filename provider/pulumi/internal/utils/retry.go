@@ -0,0 +1,151 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"syscall"
+	"time"
+
+	"cape-project.eu/provider/pulumi/config"
+)
+
+// RetryTransport retries idempotent requests that failed with a transient
+// error: 429, 502 and 503 responses as well as connections that were reset,
+// refused or cut off.
+type RetryTransport struct {
+	Base       http.RoundTripper
+	MaxRetries int
+	Backoff    Backoff
+}
+
+// NewHTTPClient returns the HTTP client shared by all API clients, retrying
+// transient errors within the limits of the provider configuration.
+func NewHTTPClient(cfg config.Config) (*http.Client, error) {
+	maxRetries := config.DefaultMaxRetries
+	if cfg.MaxRetries != nil {
+		if *cfg.MaxRetries < 0 {
+			return nil, fmt.Errorf("invalid maxRetries %d: must not be negative", *cfg.MaxRetries)
+		}
+		maxRetries = *cfg.MaxRetries
+	}
+
+	maxDelay := config.DefaultMaxRetryDelay
+	if cfg.MaxRetryDelay != nil && *cfg.MaxRetryDelay != "" {
+		delay, err := time.ParseDuration(*cfg.MaxRetryDelay)
+		if err != nil {
+			return nil, fmt.Errorf("invalid maxRetryDelay %q: %w", *cfg.MaxRetryDelay, err)
+		}
+		if delay <= 0 {
+			return nil, fmt.Errorf("invalid maxRetryDelay %q: must be positive", *cfg.MaxRetryDelay)
+		}
+		maxDelay = delay
+	}
+
+	backoff := DefaultBackoff
+	backoff.Initial = min(backoff.Initial, maxDelay)
+	backoff.Max = maxDelay
+	return &http.Client{
+		Transport: &RetryTransport{
+			Base:       http.DefaultTransport,
+			MaxRetries: maxRetries,
+			Backoff:    backoff,
+		},
+	}, nil
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *RetryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	if !isIdempotent(req) {
+		return base.RoundTrip(req)
+	}
+
+	delay := t.Backoff.Initial
+	for attempt := 0; ; attempt++ {
+		res, err := base.RoundTrip(req)
+		if attempt >= t.MaxRetries || !isTransient(res, err) || req.Context().Err() != nil {
+			return res, err
+		}
+
+		wait := jitter(delay)
+		if res != nil {
+			if retryAfter, ok := parseRetryAfter(res.Header.Get("Retry-After")); ok {
+				wait = min(retryAfter, t.Backoff.Max)
+			}
+			_, _ = io.Copy(io.Discard, res.Body)
+			_ = res.Body.Close()
+		}
+
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req = req.Clone(req.Context())
+			req.Body = body
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		case <-timer.C:
+		}
+		delay = t.Backoff.Next(delay)
+	}
+}
+
+// isIdempotent reports whether a request may be sent again. SecAPI writes
+// are PUTs of the complete resource, so only actions (POST) are excluded.
+func isIdempotent(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+	}
+	return false
+}
+
+func isTransient(res *http.Response, err error) bool {
+	if err != nil {
+		return errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED) ||
+			errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+	}
+	switch res.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable:
+		return true
+	}
+	return false
+}
+
+// jitter spreads the delay randomly over [delay/2, delay) so that clients
+// failing at the same time do not retry at the same time.
+func jitter(delay time.Duration) time.Duration {
+	half := delay / 2
+	if half <= 0 {
+		return delay
+	}
+	return half + rand.N(half)
+}
+
+// parseRetryAfter supports both forms of the Retry-After header: a number of
+// seconds or an HTTP date.
+func parseRetryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		return max(time.Until(date), 0), true
+	}
+	return 0, false
+}
@@ -0,0 +1,238 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"cape-project.eu/provider/pulumi/config"
+)
+
+var testBackoff = Backoff{Initial: time.Millisecond, Max: 5 * time.Millisecond, Factor: 2}
+
+// flakyServer answers the first failures requests with status and all
+// following ones with 200.
+func flakyServer(t *testing.T, failures int32, status int) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if calls.Add(1) <= failures {
+			w.WriteHeader(status)
+			return
+		}
+		_, _ = w.Write(body)
+	}))
+	t.Cleanup(server.Close)
+	return server, &calls
+}
+
+func TestRetryTransport(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		status     int
+		failures   int32
+		maxRetries int
+		wantStatus int
+		wantCalls  int32
+	}{
+		{name: "success", method: http.MethodGet, wantStatus: http.StatusOK, maxRetries: 3, wantCalls: 1},
+		{name: "retried 503", method: http.MethodGet, status: http.StatusServiceUnavailable, failures: 2, maxRetries: 3, wantStatus: http.StatusOK, wantCalls: 3},
+		{name: "retried 429 on put", method: http.MethodPut, status: http.StatusTooManyRequests, failures: 1, maxRetries: 3, wantStatus: http.StatusOK, wantCalls: 2},
+		{name: "retried 502 on delete", method: http.MethodDelete, status: http.StatusBadGateway, failures: 1, maxRetries: 3, wantStatus: http.StatusOK, wantCalls: 2},
+		{name: "retries exhausted", method: http.MethodGet, status: http.StatusServiceUnavailable, failures: 5, maxRetries: 2, wantStatus: http.StatusServiceUnavailable, wantCalls: 3},
+		{name: "retries disabled", method: http.MethodGet, status: http.StatusServiceUnavailable, failures: 1, wantStatus: http.StatusServiceUnavailable, wantCalls: 1},
+		{name: "permanent error", method: http.MethodGet, status: http.StatusInternalServerError, failures: 1, maxRetries: 3, wantStatus: http.StatusInternalServerError, wantCalls: 1},
+		{name: "action not retried", method: http.MethodPost, status: http.StatusServiceUnavailable, failures: 1, maxRetries: 3, wantStatus: http.StatusServiceUnavailable, wantCalls: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, calls := flakyServer(t, tt.failures, tt.status)
+			client := &http.Client{Transport: &RetryTransport{MaxRetries: tt.maxRetries, Backoff: testBackoff}}
+
+			req, err := http.NewRequest(tt.method, server.URL, strings.NewReader("payload"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			res, err := client.Do(req)
+			if err != nil {
+				t.Fatalf("request failed: %v", err)
+			}
+			defer res.Body.Close()
+			body, _ := io.ReadAll(res.Body)
+
+			if res.StatusCode != tt.wantStatus {
+				t.Errorf("status = %d, want %d", res.StatusCode, tt.wantStatus)
+			}
+			if res.StatusCode == http.StatusOK && string(body) != "payload" {
+				t.Errorf("body = %q, want the request body replayed", body)
+			}
+			if got := calls.Load(); got != tt.wantCalls {
+				t.Errorf("calls = %d, want %d", got, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestRetryTransportRetryAfter(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			w.Header().Set("Retry-After", "3600")
+			w.WriteHeader(http.StatusTooManyRequests)
+		}
+	}))
+	defer server.Close()
+
+	// Retry-After is capped by the maximum delay of the backoff.
+	client := &http.Client{Transport: &RetryTransport{MaxRetries: 1, Backoff: testBackoff}}
+	start := time.Now()
+	res, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusOK || time.Since(start) > time.Second {
+		t.Errorf("status = %d after %v, want 200 within the maximum delay", res.StatusCode, time.Since(start))
+	}
+}
+
+func TestIsTransient(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "reset", err: fmt.Errorf("read: %w", syscall.ECONNRESET), want: true},
+		{name: "refused", err: fmt.Errorf("dial: %w", syscall.ECONNREFUSED), want: true},
+		{name: "cut off", err: fmt.Errorf("read: %w", io.ErrUnexpectedEOF), want: true},
+		{name: "closed", err: io.EOF, want: true},
+		{name: "other", err: errors.New("certificate expired"), want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isTransient(nil, tt.err); got != tt.want {
+				t.Errorf("isTransient(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	if got, ok := parseRetryAfter("5"); !ok || got != 5*time.Second {
+		t.Errorf("parseRetryAfter(5) = %v, %v", got, ok)
+	}
+	date := time.Now().Add(time.Minute).UTC().Format(http.TimeFormat)
+	if got, ok := parseRetryAfter(date); !ok || got <= 0 || got > time.Minute {
+		t.Errorf("parseRetryAfter(%s) = %v, %v", date, got, ok)
+	}
+	for _, value := range []string{"", "soon", "-1"} {
+		if _, ok := parseRetryAfter(value); ok {
+			t.Errorf("parseRetryAfter(%q) succeeded", value)
+		}
+	}
+}
+
+func TestNewHTTPClient(t *testing.T) {
+	intValue := func(v int) *int { return &v }
+	stringValue := func(v string) *string { return &v }
+
+	client, err := NewHTTPClient(config.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	transport := client.Transport.(*RetryTransport)
+	if transport.MaxRetries != config.DefaultMaxRetries || transport.Backoff.Max != config.DefaultMaxRetryDelay {
+		t.Errorf("defaults = %d retries up to %v", transport.MaxRetries, transport.Backoff.Max)
+	}
+
+	client, err = NewHTTPClient(config.Config{MaxRetries: intValue(0), MaxRetryDelay: stringValue("100ms")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	transport = client.Transport.(*RetryTransport)
+	if transport.MaxRetries != 0 || transport.Backoff.Max != 100*time.Millisecond || transport.Backoff.Initial > 100*time.Millisecond {
+		t.Errorf("configured = %d retries, backoff %+v", transport.MaxRetries, transport.Backoff)
+	}
+
+	for _, cfg := range []config.Config{
+		{MaxRetries: intValue(-1)},
+		{MaxRetryDelay: stringValue("soon")},
+		{MaxRetryDelay: stringValue("0s")},
+	} {
+		if _, err := NewHTTPClient(cfg); err == nil {
+			t.Errorf("NewHTTPClient(%+v) succeeded", cfg)
+		}
+	}
+}
+
+func TestRetryTransportLostUpdate(t *testing.T) {
+	// The server applies the first update but cuts the connection before
+	// answering it, so that the retry fails its precondition.
+	var mu sync.Mutex
+	version, spec := int64(4), "small"
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if r.Method == http.MethodGet {
+			_, _ = fmt.Fprintf(w, "%d %s", version, spec)
+			return
+		}
+
+		body, _ := io.ReadAll(r.Body)
+		if r.Header.Get("If-Unmodified-Since") != strconv.FormatInt(version, 10) {
+			w.WriteHeader(http.StatusPreconditionFailed)
+			return
+		}
+		version, spec = version+1, string(body)
+		if calls.Add(1) == 1 {
+			conn, _, err := w.(http.Hijacker).Hijack()
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			_ = conn.Close()
+		}
+	}))
+	defer server.Close()
+
+	client := &http.Client{Transport: &RetryTransport{MaxRetries: 2, Backoff: testBackoff}}
+	req, err := http.NewRequest(http.MethodPut, server.URL, strings.NewReader("large"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("If-Unmodified-Since", "4")
+	res, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("update failed: %v", err)
+	}
+	res.Body.Close()
+	if !IsConflict(res.StatusCode) {
+		t.Fatalf("status = %d, want the retry to fail its precondition", res.StatusCode)
+	}
+
+	res, err = client.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	var currentVersion int64
+	var current string
+	if _, err := fmt.Fscan(res.Body, &currentVersion, &current); err != nil {
+		t.Fatal(err)
+	}
+	if !UpdateApplied("large", current, 4, currentVersion) {
+		t.Errorf("UpdateApplied() = false for version %d with spec %q, want the lost update detected", currentVersion, current)
+	}
+}
@@ -0,0 +1,100 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"cape-project.eu/provider/pulumi/secapi/models"
+)
+
+// DefaultOperationTimeout bounds create, update and delete operations whose
+// timeout is not configured on the provider.
+const DefaultOperationTimeout = 20 * time.Minute
+
+// Backoff describes the exponential delay between two polls.
+type Backoff struct {
+	Initial time.Duration
+	Max     time.Duration
+	Factor  float64
+}
+
+// DefaultBackoff is used when waiting on asynchronous SecAPI operations.
+var DefaultBackoff = Backoff{
+	Initial: 250 * time.Millisecond,
+	Max:     10 * time.Second,
+	Factor:  2,
+}
+
+// Next returns the delay following the given one.
+func (b Backoff) Next(delay time.Duration) time.Duration {
+	next := time.Duration(float64(delay) * b.Factor)
+	if next > b.Max {
+		return b.Max
+	}
+	return next
+}
+
+// OperationTimeout parses a timeout from the provider configuration. Values
+// use Go duration syntax (e.g. "20m"); nil or empty values fall back to
+// DefaultOperationTimeout. If ctx has an earlier deadline, as set from the
+// customTimeouts of a resource, the time left until then is returned instead.
+func OperationTimeout(ctx context.Context, value *string) (time.Duration, error) {
+	timeout := DefaultOperationTimeout
+	if value != nil && *value != "" {
+		var err error
+		timeout, err = time.ParseDuration(*value)
+		if err != nil {
+			return 0, fmt.Errorf("invalid timeout %q: %w", *value, err)
+		}
+		if timeout <= 0 {
+			return 0, fmt.Errorf("invalid timeout %q: must be positive", *value)
+		}
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		timeout = min(timeout, time.Until(deadline))
+	}
+	return timeout, nil
+}
+
+// Poll calls check until it reports done or fails, sleeping according to
+// backoff in between. It returns the context error once ctx is done.
+func Poll(ctx context.Context, backoff Backoff, check func() (bool, error)) error {
+	delay := backoff.Initial
+	for {
+		done, err := check()
+		if err != nil {
+			return err
+		}
+		if done {
+			return nil
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+		delay = backoff.Next(delay)
+	}
+}
+
+// LastConditionMessage returns the message of the most recent status
+// condition, or an empty string if there is none.
+func LastConditionMessage(conditions []models.StatusCondition) string {
+	var last *models.StatusCondition
+	for i := range conditions {
+		if last == nil || !conditions[i].LastTransitionAt.Before(last.LastTransitionAt) {
+			last = &conditions[i]
+		}
+	}
+	if last == nil {
+		return ""
+	}
+	if last.Reason != "" {
+		return fmt.Sprintf("%s (%s)", last.Message, last.Reason)
+	}
+	return last.Message
+}
@@ -0,0 +1,105 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestOperationTimeout(t *testing.T) {
+	value := func(s string) *string { return &s }
+
+	tests := []struct {
+		name     string
+		value    *string
+		deadline time.Duration
+		want     time.Duration
+		wantErr  bool
+	}{
+		{name: "default", want: DefaultOperationTimeout},
+		{name: "empty", value: value(""), want: DefaultOperationTimeout},
+		{name: "configured", value: value("5m"), want: 5 * time.Minute},
+		{name: "invalid", value: value("soon"), wantErr: true},
+		{name: "not positive", value: value("0s"), wantErr: true},
+		{name: "shorter deadline", value: value("5m"), deadline: time.Minute, want: time.Minute},
+		{name: "longer deadline", value: value("5m"), deadline: time.Hour, want: 5 * time.Minute},
+		{name: "deadline without config", deadline: time.Minute, want: time.Minute},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.deadline > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, tt.deadline)
+				defer cancel()
+			}
+
+			got, err := OperationTimeout(ctx, tt.value)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("OperationTimeout() = %v, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("OperationTimeout() failed: %v", err)
+			}
+			// Allow for the time passed since the deadline was set.
+			if got > tt.want || got < tt.want-time.Second {
+				t.Errorf("OperationTimeout() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBackoffNext(t *testing.T) {
+	backoff := Backoff{Initial: time.Second, Max: 5 * time.Second, Factor: 2}
+
+	var got []time.Duration
+	for delay := backoff.Initial; len(got) < 4; delay = backoff.Next(delay) {
+		got = append(got, delay)
+	}
+	want := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("delays = %v, want %v", got, want)
+		}
+	}
+}
+
+func TestPoll(t *testing.T) {
+	backoff := Backoff{Initial: time.Millisecond, Max: time.Millisecond, Factor: 2}
+
+	t.Run("done", func(t *testing.T) {
+		calls := 0
+		err := Poll(context.Background(), backoff, func() (bool, error) {
+			calls++
+			return calls == 3, nil
+		})
+		if err != nil || calls != 3 {
+			t.Fatalf("Poll() = %v after %d calls, want nil after 3", err, calls)
+		}
+	})
+
+	t.Run("check fails", func(t *testing.T) {
+		failure := errors.New("failed")
+		err := Poll(context.Background(), backoff, func() (bool, error) {
+			return false, failure
+		})
+		if !errors.Is(err, failure) {
+			t.Fatalf("Poll() = %v, want %v", err, failure)
+		}
+	})
+
+	t.Run("deadline", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		err := Poll(ctx, backoff, func() (bool, error) {
+			return false, nil
+		})
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("Poll() = %v, want %v", err, context.DeadlineExceeded)
+		}
+	})
+}